@@ -0,0 +1,296 @@
+// Package analysis reviews finished games with a chess engine
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Classification describes how much a move lost compared to the engine's best move
+type Classification string
+
+// Move classifications by centipawn loss
+const (
+	Good       Classification = "good"
+	Inaccuracy Classification = "inaccuracy"
+	Mistake    Classification = "mistake"
+	Blunder    Classification = "blunder"
+)
+
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+	// evaluations are capped so that a missed mate doesn't count as thousands of centipawns
+	maxCP = 1000
+)
+
+// MoveReport is the engine's verdict on a single move
+type MoveReport struct {
+	// Ply is the index of the move in the game starting at 0 for White's first move
+	Ply  int
+	SAN  string
+	Best string
+	// Before and After are the evaluations around the move from White's point of view
+	Before game.Evaluation
+	After  game.Evaluation
+	// CPLoss is how many centipawns the move lost compared to the best move
+	CPLoss         int
+	Accuracy       float64
+	Classification Classification
+}
+
+// Notation returns the move with its move number, like 12. Qxb7 or 12... Nc6
+func (m MoveReport) Notation() string {
+	if m.Ply%2 == 0 {
+		return fmt.Sprintf("%d. %s", m.Ply/2+1, m.SAN)
+	}
+	return fmt.Sprintf("%d... %s", m.Ply/2+1, m.SAN)
+}
+
+// Report is the analysis of every move one side played in a game
+type Report struct {
+	Color        game.Color
+	Moves        []MoveReport
+	Accuracy     float64
+	Inaccuracies int
+	Mistakes     int
+	Blunders     int
+	// Positions holds the evaluation and best move of every position in the game, for both sides
+	Positions []PositionReport
+}
+
+// PositionReport is the engine's evaluation of a position before a move is played
+type PositionReport struct {
+	Eval game.Evaluation
+	// Best is the engine's best move in standard algebraic notation, empty when the game is over
+	Best string
+}
+
+// Worst returns up to n of the moves that lost the most, worst first. Good moves are never included
+func (r Report) Worst(n int) []MoveReport {
+	worst := []MoveReport{}
+	for _, move := range r.Moves {
+		if move.Classification != Good {
+			worst = append(worst, move)
+		}
+	}
+	sort.SliceStable(worst, func(i, j int) bool {
+		return worst[i].CPLoss > worst[j].CPLoss
+	})
+	if len(worst) > n {
+		worst = worst[:n]
+	}
+	return worst
+}
+
+// Engine searches the positions of a finished game
+type Engine interface {
+	// Move returns the best move in the position along with its evaluation from White's point of view
+	Move(position *chess.Position) (*chess.Move, game.Evaluation, error)
+	Close() error
+}
+
+// stockfish is a full strength engine searching every position for a fixed time
+type stockfish struct {
+	eng      *uci.Engine
+	moveTime time.Duration
+}
+
+// NewStockfish starts the engine found at path. Every position is searched for moveTime
+func NewStockfish(path string, moveTime time.Duration) (Engine, error) {
+	eng, err := uci.New(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdUCINewGame); err != nil {
+		eng.Close()
+		return nil, err
+	}
+	return stockfish{eng: eng, moveTime: moveTime}, nil
+}
+
+func (s stockfish) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	cmdPos := uci.CmdPosition{Position: position}
+	cmdGo := uci.CmdGo{MoveTime: s.moveTime}
+	if err := s.eng.Run(cmdPos, cmdGo); err != nil {
+		return nil, game.Evaluation{}, err
+	}
+	results := s.eng.SearchResults()
+
+	// the engine scores from the point of view of the side to move
+	eval := game.Evaluation{CP: results.Info.Score.CP, Mate: results.Info.Score.Mate}
+	if position.Turn() == chess.Black {
+		eval.CP, eval.Mate = -eval.CP, -eval.Mate
+	}
+	return results.BestMove, eval, nil
+}
+
+func (s stockfish) Close() error {
+	return s.eng.Close()
+}
+
+// Analyzer runs an engine over finished games
+type Analyzer struct {
+	eng Engine
+}
+
+// New returns an Analyzer searching with the engine, closing the analyzer closes the engine
+func New(eng Engine) *Analyzer {
+	return &Analyzer{eng: eng}
+}
+
+// Close stops the engine
+func (a *Analyzer) Close() {
+	a.eng.Close()
+}
+
+// Analyze evaluates every position of the game and reports on the moves played by color
+func (a *Analyzer) Analyze(moves []*chess.Move, color game.Color) (Report, error) {
+	report := Report{Color: color}
+
+	positions := []*chess.Position{chess.StartingPosition()}
+	for _, move := range moves {
+		positions = append(positions, positions[len(positions)-1].Update(move))
+	}
+
+	for _, position := range positions {
+		evaluated, err := a.evaluate(position)
+		if err != nil {
+			return report, err
+		}
+		report.Positions = append(report.Positions, evaluated)
+	}
+
+	var accuracySum float64
+	for ply, move := range moves {
+		mover := positions[ply].Turn()
+		if mover != game.ColorMap[color] {
+			continue
+		}
+
+		before, after := report.Positions[ply].Eval, report.Positions[ply+1].Eval
+		san := chess.AlgebraicNotation{}.Encode(positions[ply], move)
+		moveReport := MoveReport{
+			Ply:    ply,
+			SAN:    san,
+			Best:   report.Positions[ply].Best,
+			Before: before,
+			After:  after,
+		}
+
+		if san != moveReport.Best {
+			moveReport.CPLoss = max(0, centipawns(before, mover)-centipawns(after, mover))
+		}
+		moveReport.Accuracy = moveAccuracy(winPercent(before, mover), winPercent(after, mover))
+		moveReport.Classification = classify(moveReport.CPLoss)
+
+		switch moveReport.Classification {
+		case Inaccuracy:
+			report.Inaccuracies++
+		case Mistake:
+			report.Mistakes++
+		case Blunder:
+			report.Blunders++
+		}
+		accuracySum += moveReport.Accuracy
+		report.Moves = append(report.Moves, moveReport)
+	}
+
+	if len(report.Moves) > 0 {
+		report.Accuracy = accuracySum / float64(len(report.Moves))
+	}
+	return report, nil
+}
+
+// evaluate searches a single position. Finished games are scored without asking the engine
+func (a *Analyzer) evaluate(position *chess.Position) (PositionReport, error) {
+	switch position.Status() {
+	case chess.Checkmate:
+		// the side to move has been mated
+		if position.Turn() == chess.White {
+			return PositionReport{Eval: game.Evaluation{Mate: -1}}, nil
+		}
+		return PositionReport{Eval: game.Evaluation{Mate: 1}}, nil
+	case chess.Stalemate:
+		return PositionReport{}, nil
+	}
+
+	bestMove, eval, err := a.eng.Move(position)
+	if err != nil {
+		return PositionReport{}, err
+	}
+
+	best := ""
+	if bestMove != nil {
+		for _, move := range position.ValidMoves() {
+			if game.SameMove(move, bestMove) {
+				best = chess.AlgebraicNotation{}.Encode(position, move)
+				break
+			}
+		}
+	}
+	return PositionReport{Eval: eval, Best: best}, nil
+}
+
+// centipawns converts an evaluation into capped centipawns from the point of view of color
+func centipawns(e game.Evaluation, color chess.Color) int {
+	cp := e.CP
+	switch {
+	case e.Mate > 0:
+		cp = maxCP
+	case e.Mate < 0:
+		cp = -maxCP
+	}
+	cp = max(-maxCP, min(maxCP, cp))
+	if color == chess.Black {
+		return -cp
+	}
+	return cp
+}
+
+// winPercent converts an evaluation into the chance of winning for color, between 0 and 100
+func winPercent(e game.Evaluation, color chess.Color) float64 {
+	cp := float64(centipawns(e, color))
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*cp))-1)
+}
+
+// moveAccuracy scores a move between 0 and 100 by how much winning chance it gave away
+func moveAccuracy(winBefore float64, winAfter float64) float64 {
+	accuracy := 103.1668*math.Exp(-0.04354*(winBefore-winAfter)) - 3.1669
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+func classify(cpLoss int) Classification {
+	switch {
+	case cpLoss >= blunderLoss:
+		return Blunder
+	case cpLoss >= mistakeLoss:
+		return Mistake
+	case cpLoss >= inaccuracyLoss:
+		return Inaccuracy
+	default:
+		return Good
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
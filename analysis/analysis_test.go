@@ -0,0 +1,183 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// fakeEngine answers with evaluations and best moves given in advance for each position
+type fakeEngine struct {
+	// positions maps the FEN of a position to its evaluation and best move in algebraic notation
+	positions map[string]PositionReport
+}
+
+func (e fakeEngine) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	report := e.positions[position.String()]
+	return decode(position, report.Best), report.Eval, nil
+}
+
+func (e fakeEngine) Close() error {
+	return nil
+}
+
+// play returns the moves and the positions before and after each of them
+func play(t *testing.T, sans ...string) ([]*chess.Move, []*chess.Position) {
+	t.Helper()
+	positions := []*chess.Position{chess.StartingPosition()}
+	var moves []*chess.Move
+	for _, san := range sans {
+		position := positions[len(positions)-1]
+		move, err := chess.AlgebraicNotation{}.Decode(position, san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves = append(moves, move)
+		positions = append(positions, position.Update(move))
+	}
+	return moves, positions
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		cpLoss int
+		want   Classification
+	}{
+		{0, Good},
+		{49, Good},
+		{50, Inaccuracy},
+		{99, Inaccuracy},
+		{100, Mistake},
+		{299, Mistake},
+		{300, Blunder},
+		{5000, Blunder},
+	}
+
+	for _, tt := range tests {
+		if got := classify(tt.cpLoss); got != tt.want {
+			t.Errorf("classify(%d) = %s, want %s", tt.cpLoss, got, tt.want)
+		}
+	}
+}
+
+func TestMoveAccuracy(t *testing.T) {
+	tests := []struct {
+		name      string
+		winBefore float64
+		winAfter  float64
+		want      float64
+	}{
+		{"nothing given away", 60, 60, 100},
+		{"winning chances improved", 40, 70, 100},
+		{"ten points given away", 60, 50, 63.58},
+		{"a won game thrown away", 100, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := moveAccuracy(tt.winBefore, tt.winAfter)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Fatalf("got %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorst(t *testing.T) {
+	report := Report{Moves: []MoveReport{
+		{SAN: "e4", CPLoss: 0, Classification: Good},
+		{SAN: "a3", CPLoss: 60, Classification: Inaccuracy},
+		{SAN: "Qh5", CPLoss: 350, Classification: Blunder},
+		{SAN: "Nf3", CPLoss: 20, Classification: Good},
+		{SAN: "h4", CPLoss: 150, Classification: Mistake},
+		{SAN: "b4", CPLoss: 60, Classification: Inaccuracy},
+	}}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, []string{}},
+		{2, []string{"Qh5", "h4"}},
+		// moves that lost as much keep their order
+		{10, []string{"Qh5", "h4", "a3", "b4"}},
+	}
+
+	for _, tt := range tests {
+		worst := report.Worst(tt.n)
+		var got []string
+		for _, move := range worst {
+			got = append(got, move.SAN)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("Worst(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("Worst(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		}
+	}
+
+	if worst := (Report{}).Worst(3); worst == nil || len(worst) != 0 {
+		t.Fatalf("got %v, want an empty list for a report without moves", worst)
+	}
+}
+
+func TestAnalyzeReportsTheMovesOfOneSide(t *testing.T) {
+	moves, positions := play(t, "e4", "e5", "Qh5", "Nc6")
+	evals := []PositionReport{
+		{Eval: game.Evaluation{CP: 30}, Best: "e4"},
+		{Eval: game.Evaluation{CP: 30}, Best: "e5"},
+		{Eval: game.Evaluation{CP: 30}, Best: "Nf3"},
+		{Eval: game.Evaluation{CP: -300}, Best: "Nc6"},
+		{Eval: game.Evaluation{CP: -300}, Best: "Bc4"},
+	}
+	eng := fakeEngine{positions: make(map[string]PositionReport)}
+	for i, position := range positions {
+		eng.positions[position.String()] = evals[i]
+	}
+
+	analyzer := New(eng)
+	defer analyzer.Close()
+	report, err := analyzer.Analyze(moves, game.White)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Positions) != len(positions) {
+		t.Fatalf("got %d evaluated positions, want %d", len(report.Positions), len(positions))
+	}
+	if len(report.Moves) != 2 {
+		t.Fatalf("got %d moves, want the 2 moves of White", len(report.Moves))
+	}
+	if e4 := report.Moves[0]; e4.SAN != "e4" || e4.CPLoss != 0 || e4.Classification != Good {
+		t.Fatalf("got %+v, want e4 to be the engine's move", e4)
+	}
+	qh5 := report.Moves[1]
+	if qh5.Notation() != "2. Qh5" || qh5.Best != "Nf3" || qh5.CPLoss != 330 || qh5.Classification != Blunder {
+		t.Fatalf("got %+v, want Qh5 to be a blunder of 330 centipawns", qh5)
+	}
+	if report.Blunders != 1 || report.Mistakes != 0 || report.Inaccuracies != 0 {
+		t.Fatalf("got %d blunders, %d mistakes and %d inaccuracies", report.Blunders, report.Mistakes, report.Inaccuracies)
+	}
+	if want := (report.Moves[0].Accuracy + report.Moves[1].Accuracy) / 2; report.Accuracy != want {
+		t.Fatalf("got an accuracy of %.2f, want the average %.2f", report.Accuracy, want)
+	}
+}
+
+func TestAnalyzeScoresMateWithoutTheEngine(t *testing.T) {
+	moves, _ := play(t, "f3", "e5", "g4", "Qh4#")
+	// every position but the final one has an evaluation of 0
+	analyzer := New(fakeEngine{positions: map[string]PositionReport{}})
+	report, err := analyzer.Analyze(moves, game.Black)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final := report.Positions[len(report.Positions)-1]; final.Eval.Mate != -1 || final.Best != "" {
+		t.Fatalf("got %+v for the mated position, want Black mating", final)
+	}
+}
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// VoterStats is how a single player's votes compare to the engine and to the rest of the channel
+type VoterStats struct {
+	PlayerID string
+	Votes    int
+	// EngineMatches counts the votes for the engine's best move
+	EngineMatches int
+	// CrowdMatches counts the votes for the move that ended up being played
+	CrowdMatches int
+}
+
+// EngineAgreement is the share of votes that went to the engine's best move, between 0 and 100
+func (v VoterStats) EngineAgreement() float64 {
+	if v.Votes == 0 {
+		return 0
+	}
+	return 100 * float64(v.EngineMatches) / float64(v.Votes)
+}
+
+// CrowdAgreement is the share of votes that went to the played move, between 0 and 100
+func (v VoterStats) CrowdAgreement() float64 {
+	if v.Votes == 0 {
+		return 0
+	}
+	return 100 * float64(v.CrowdMatches) / float64(v.Votes)
+}
+
+// Voters compares every vote of the game with the engine's best move from the report and with the crowd's choice.
+// The result is sorted as a leaderboard, best engine agreement first
+func Voters(moves []*chess.Move, rounds []game.VoteRound, report Report) []VoterStats {
+	positions := []*chess.Position{chess.StartingPosition()}
+	for _, move := range moves {
+		positions = append(positions, positions[len(positions)-1].Update(move))
+	}
+
+	stats := make(map[string]*VoterStats)
+	for _, round := range rounds {
+		if round.Ply >= len(moves) || round.Ply >= len(report.Positions) {
+			continue
+		}
+		position := positions[round.Ply]
+		// votes are compared as moves rather than text so Nf3, Ngf3 and g1f3 count as the same vote
+		best := decode(position, report.Positions[round.Ply].Best)
+		played := moves[round.Ply]
+
+		for playerID, san := range round.Votes {
+			voter, ok := stats[playerID]
+			if !ok {
+				voter = &VoterStats{PlayerID: playerID}
+				stats[playerID] = voter
+			}
+			voter.Votes++
+
+			vote := decode(position, san)
+			if vote == nil {
+				continue
+			}
+			if game.SameMove(vote, best) {
+				voter.EngineMatches++
+			}
+			if game.SameMove(vote, played) {
+				voter.CrowdMatches++
+			}
+		}
+	}
+
+	leaderboard := make([]VoterStats, 0, len(stats))
+	for _, voter := range stats {
+		leaderboard = append(leaderboard, *voter)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		a, b := leaderboard[i], leaderboard[j]
+		if a.EngineAgreement() != b.EngineAgreement() {
+			return a.EngineAgreement() > b.EngineAgreement()
+		}
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	return leaderboard
+}
+
+// decode reads a move written the way players vote, returning nil for empty or invalid moves
+func decode(position *chess.Position, san string) *chess.Move {
+	move, err := game.DecodeMove(position, san)
+	if err != nil {
+		return nil
+	}
+	return move
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+func TestVoters(t *testing.T) {
+	moves, _ := play(t, "e4", "e5", "Nf3")
+	report := Report{Positions: []PositionReport{
+		{Best: "e4"},
+		// the engine had no move to suggest
+		{Best: ""},
+		{Best: "Nf3"},
+		{Best: "Nc6"},
+	}}
+
+	tests := []struct {
+		name   string
+		rounds []game.VoteRound
+		want   []VoterStats
+	}{
+		{
+			name: "engine and played move",
+			rounds: []game.VoteRound{
+				{Ply: 0, Votes: map[string]string{"U1": "e4", "U2": "d4"}, Played: "e4"},
+			},
+			want: []VoterStats{
+				{PlayerID: "U1", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U2", Votes: 1},
+			},
+		},
+		{
+			name: "same move in other notations",
+			rounds: []game.VoteRound{
+				{Ply: 2, Votes: map[string]string{"U1": "Ngf3", "U2": "Nf3", "U3": "Nc3", "U4": "g1f3"}, Played: "Nf3"},
+			},
+			want: []VoterStats{
+				{PlayerID: "U1", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U2", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U4", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U3", Votes: 1},
+			},
+		},
+		{
+			name: "without a best move only the played move matches",
+			rounds: []game.VoteRound{
+				{Ply: 1, Votes: map[string]string{"U1": "e5"}, Played: "e5"},
+			},
+			want: []VoterStats{
+				{PlayerID: "U1", Votes: 1, CrowdMatches: 1},
+			},
+		},
+		{
+			name: "illegal votes count without matching",
+			rounds: []game.VoteRound{
+				{Ply: 0, Votes: map[string]string{"U1": "Ke2", "U2": "e4"}, Played: "e4"},
+			},
+			want: []VoterStats{
+				{PlayerID: "U2", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U1", Votes: 1},
+			},
+		},
+		{
+			name: "rounds past the last move are left out",
+			rounds: []game.VoteRound{
+				{Ply: 3, Votes: map[string]string{"U1": "Nc6"}, Played: "Nc6"},
+			},
+			want: []VoterStats{},
+		},
+		{
+			name: "sorted by engine agreement then votes",
+			rounds: []game.VoteRound{
+				{Ply: 0, Votes: map[string]string{"U1": "d4", "U2": "e4", "U3": "e4"}, Played: "e4"},
+				{Ply: 1, Votes: map[string]string{"U1": "e5", "U3": "e5"}, Played: "e5"},
+				{Ply: 2, Votes: map[string]string{"U1": "Nf3", "U3": "Nf3"}, Played: "Nf3"},
+			},
+			want: []VoterStats{
+				{PlayerID: "U2", Votes: 1, EngineMatches: 1, CrowdMatches: 1},
+				{PlayerID: "U3", Votes: 3, EngineMatches: 2, CrowdMatches: 3},
+				{PlayerID: "U1", Votes: 3, EngineMatches: 1, CrowdMatches: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Voters(moves, tt.rounds, report)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVoterAgreement(t *testing.T) {
+	voter := VoterStats{Votes: 4, EngineMatches: 1, CrowdMatches: 3}
+	if got := voter.EngineAgreement(); got != 25 {
+		t.Fatalf("got %v, want 25", got)
+	}
+	if got := voter.CrowdAgreement(); got != 75 {
+		t.Fatalf("got %v, want 75", got)
+	}
+	if got := (VoterStats{}).EngineAgreement(); got != 0 {
+		t.Fatalf("got %v, want 0 without votes", got)
+	}
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+// maxBodySize bounds the size of request bodies, a vote is only a few bytes
+const maxBodySize = 4 * 1024
+
+// apiPlayerPrefix sets the IDs of API voters apart from Slack user IDs, so token holders
+// can't vote or collect statistics as the members of the channel
+const apiPlayerPrefix = "api:"
+
+// playerIDRegex limits the IDs API voters pick to something safe to show everywhere
+var playerIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,20}$`)
+
+// Handler serves the JSON API under /api/v1/ for the games of the store
+type Handler struct {
+	GameStorage game.ChessStorage
+	// Token authenticates the clients, every request needs an "Authorization: Bearer <Token>" header.
+	// An empty token rejects every request
+	Token string
+}
+
+// Statuses of a game
+const (
+	statusActive   = "active"
+	statusFinished = "finished"
+)
+
+// gameSummary is a game in the list of games
+type gameSummary struct {
+	ID         string      `json:"id"`
+	Status     string      `json:"status"`
+	HumanColor game.Color  `json:"human_color"`
+	Result     game.Result `json:"result,omitempty"`
+	Moves      int         `json:"moves"`
+	StartedAt  time.Time   `json:"started_at"`
+	EndedAt    *time.Time  `json:"ended_at,omitempty"`
+}
+
+// gameDetail is everything known about a single game
+type gameDetail struct {
+	gameSummary
+	FEN     string                `json:"fen"`
+	PGN     string                `json:"pgn"`
+	Turn    game.Color            `json:"turn,omitempty"`
+	Method  string                `json:"method,omitempty"`
+	Players map[game.Color]string `json:"players"`
+	// Participants are everybody who voted in a finished game
+	Participants []string         `json:"participants,omitempty"`
+	Votes        []game.VoteCount `json:"votes,omitempty"`
+	Timers       *timers          `json:"timers,omitempty"`
+}
+
+// timers tell when the current turn and the game are up
+type timers struct {
+	LastMoveAt time.Time `json:"last_move_at"`
+	// VoteDeadline is when the top voted move gets played, missing while nobody has voted
+	VoteDeadline       *time.Time `json:"vote_deadline,omitempty"`
+	InactivityDeadline time.Time  `json:"inactivity_deadline"`
+}
+
+// votesResponse holds the current votes of an active game or the votes of every turn of a finished game
+type votesResponse struct {
+	ID      string           `json:"id"`
+	Status  string           `json:"status"`
+	Votes   []game.VoteCount `json:"votes,omitempty"`
+	History []game.VoteRound `json:"history,omitempty"`
+}
+
+// voteRequest is the body of a vote
+type voteRequest struct {
+	PlayerID string `json:"player_id"`
+	Move     string `json:"move"`
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="collab-chess"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/games"), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodGet: h.listGames})
+	case len(parts) == 1:
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { h.getGame(w, parts[0]) },
+		})
+	case len(parts) == 2 && parts[1] == "votes":
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  func(w http.ResponseWriter, r *http.Request) { h.getVotes(w, parts[0]) },
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { h.postVote(w, r, parts[0]) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authorized checks the bearer token of the request in constant time
+func (h Handler) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if h.Token == "" || token == "" || token == header {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) == 1
+}
+
+// route calls the handler of the request method or answers with 405
+func (h Handler) route(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	if handler, ok := handlers[r.Method]; ok {
+		handler(w, r)
+		return
+	}
+
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func (h Handler) listGames(w http.ResponseWriter, r *http.Request) {
+	games := []gameSummary{}
+	if gm, err := h.GameStorage.RetrieveGame(); err == nil {
+		games = append(games, activeSummary(gm.Snapshot()))
+	}
+
+	records, err := h.GameStorage.Records()
+	if err != nil {
+		log.Println("could not load the game records:", err)
+		writeError(w, http.StatusInternalServerError, "could not load the games")
+		return
+	}
+	// latest games first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].EndedAt.After(records[j].EndedAt)
+	})
+	for _, record := range records {
+		games = append(games, finishedSummary(record))
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Games []gameSummary `json:"games"`
+	}{games})
+}
+
+func (h Handler) getGame(w http.ResponseWriter, id string) {
+	if gm, ok := h.activeGame(id); ok {
+		writeJSON(w, http.StatusOK, activeDetail(gm.Snapshot()))
+		return
+	}
+
+	record, err := h.GameStorage.Record(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("there is no game with ID %s", id))
+		return
+	}
+	detail, err := finishedDetail(record)
+	if err != nil {
+		log.Println("could not replay game", record.ID, err)
+		writeError(w, http.StatusInternalServerError, "could not replay the game")
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h Handler) getVotes(w http.ResponseWriter, id string) {
+	if gm, ok := h.activeGame(id); ok {
+		snapshot := gm.Snapshot()
+		writeJSON(w, http.StatusOK, votesResponse{
+			ID:      snapshot.ID,
+			Status:  statusActive,
+			Votes:   game.TallyVotes(snapshot.Votes),
+			History: snapshot.VoteHistory,
+		})
+		return
+	}
+
+	record, err := h.GameStorage.Record(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("there is no game with ID %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, votesResponse{
+		ID:      record.ID,
+		Status:  statusFinished,
+		History: record.VoteHistory,
+	})
+}
+
+// postVote votes the same way a !move message in Slack does
+func (h Handler) postVote(w http.ResponseWriter, r *http.Request, id string) {
+	gm, ok := h.activeGame(id)
+	if !ok {
+		if _, err := h.GameStorage.Record(id); err == nil {
+			writeError(w, http.StatusConflict, "the game is already over")
+			return
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("there is no active game with ID %s", id))
+		return
+	}
+
+	var vote voteRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&vote); err != nil {
+		writeError(w, http.StatusBadRequest, "the body must be a JSON object with player_id and move")
+		return
+	}
+	if vote.PlayerID == "" || vote.Move == "" {
+		writeError(w, http.StatusBadRequest, "player_id and move are required")
+		return
+	}
+	if !playerIDRegex.MatchString(vote.PlayerID) {
+		writeError(w, http.StatusBadRequest, "player_id must be 1 to 20 letters, digits, _ or -")
+		return
+	}
+
+	if gm.TurnPlayer().ID == "chessbot" {
+		writeError(w, http.StatusConflict, "it is the bot's turn")
+		return
+	}
+
+	if err := gm.Vote(apiPlayerPrefix+vote.PlayerID, vote.Move); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s is not a valid move", vote.Move))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, votesResponse{
+		ID:     gm.ID,
+		Status: statusActive,
+		Votes:  game.TallyVotes(gm.Votes()),
+	})
+}
+
+// activeGame returns the game in play if it has the given ID
+func (h Handler) activeGame(id string) (*game.Game, bool) {
+	gm, err := h.GameStorage.RetrieveGame()
+	if err != nil || gm.ID != id {
+		return nil, false
+	}
+	return gm, true
+}
+
+func activeSummary(snapshot game.Snapshot) gameSummary {
+	return gameSummary{
+		ID:         snapshot.ID,
+		Status:     statusActive,
+		HumanColor: snapshot.HumanColor,
+		Moves:      len(snapshot.Moves),
+		StartedAt:  snapshot.CreatedAt,
+	}
+}
+
+func finishedSummary(record game.Record) gameSummary {
+	endedAt := record.EndedAt
+	return gameSummary{
+		ID:         record.ID,
+		Status:     statusFinished,
+		HumanColor: record.HumanColor,
+		Result:     record.Result,
+		Moves:      len(record.Moves),
+		StartedAt:  record.StartedAt,
+		EndedAt:    &endedAt,
+	}
+}
+
+func activeDetail(snapshot game.Snapshot) gameDetail {
+	players := make(map[game.Color]string)
+	for color, player := range snapshot.Players {
+		players[color] = player.ID
+	}
+
+	t := &timers{
+		LastMoveAt:         snapshot.LastMoveTime,
+		InactivityDeadline: snapshot.InactivityDeadline,
+	}
+	if !snapshot.VoteDeadline.IsZero() {
+		deadline := snapshot.VoteDeadline
+		t.VoteDeadline = &deadline
+	}
+
+	return gameDetail{
+		gameSummary: activeSummary(snapshot),
+		FEN:         snapshot.FEN,
+		PGN:         snapshot.PGN,
+		Turn:        snapshot.Turn,
+		Players:     players,
+		Votes:       game.TallyVotes(snapshot.Votes),
+		Timers:      t,
+	}
+}
+
+func finishedDetail(record game.Record) (gameDetail, error) {
+	fen, err := record.FEN()
+	if err != nil {
+		return gameDetail{}, err
+	}
+
+	// the first participant is the player who started the game
+	players := map[game.Color]string{record.HumanColor.Other(): "chessbot"}
+	if len(record.Participants) > 0 {
+		players[record.HumanColor] = record.Participants[0]
+	}
+
+	return gameDetail{
+		gameSummary:  finishedSummary(record),
+		FEN:          fen,
+		PGN:          record.PGN,
+		Method:       record.Method,
+		Players:      players,
+		Participants: record.Participants,
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println("could not write the response:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
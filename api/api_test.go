@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+const testToken = "test-token"
+
+// newTestHandler returns a handler over a store with an active game "live" where the human players
+// play the given color, and a finished game "done"
+func newTestHandler(t *testing.T, humanColor string) (Handler, *game.Game) {
+	t.Helper()
+	store := game.NewMemoryStore()
+	gm := game.NewGame("live", humanColor, game.Player{ID: "chessbot"}, game.Player{ID: "U1"})
+	if err := store.StoreGame(gm); err != nil {
+		t.Fatal(err)
+	}
+	err := store.SaveRecord(game.Record{
+		ID:           "done",
+		EndedAt:      time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		HumanColor:   game.White,
+		Result:       game.Loss,
+		Method:       "Checkmate",
+		Participants: []string{"U1", "U2"},
+		Moves:        []string{"f2f3", "e7e5", "g2g4", "d8h4"},
+		PGN:          "1. f3 e5 2. g4 Qh4# 0-1",
+		VoteHistory:  []game.VoteRound{{Ply: 0, Votes: map[string]string{"U2": "f3"}, Played: "f3"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Handler{GameStorage: store, Token: testToken}, gm
+}
+
+// request sends a request with the token to the handler
+func request(h Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decode reads the JSON body of the response into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("got content type %q, want JSON", got)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("the body %q is not JSON: %v", w.Body.String(), err)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	h, _ := newTestHandler(t, "white")
+
+	tests := []struct {
+		name       string
+		handler    Handler
+		header     string
+		wantStatus int
+	}{
+		{"no header", h, "", http.StatusUnauthorized},
+		{"empty token", h, "Bearer ", http.StatusUnauthorized},
+		{"wrong token", h, "Bearer nope", http.StatusUnauthorized},
+		{"token without the scheme", h, testToken, http.StatusUnauthorized},
+		{"right token", h, "Bearer " + testToken, http.StatusOK},
+		{"no token set", Handler{GameStorage: h.GameStorage}, "Bearer ", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/games", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusUnauthorized {
+				return
+			}
+			var body errorResponse
+			decode(t, w, &body)
+			if body.Error != "missing or invalid token" || w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("got %+v with WWW-Authenticate %q", body, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRouting(t *testing.T) {
+	h, _ := newTestHandler(t, "white")
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{http.MethodGet, "/api/v1/games", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/games/", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/games/live", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/games/done", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/games/live/votes", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/games/missing", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/games/missing/votes", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/games/live/moves", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/games/live/votes/e4", http.StatusNotFound, ""},
+		{http.MethodPost, "/api/v1/games", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodDelete, "/api/v1/games/live", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodPut, "/api/v1/games/live/votes", http.StatusMethodNotAllowed, "GET, POST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := request(h, tt.method, tt.path, testToken, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Fatalf("got Allow %q, want %q", got, tt.wantAllow)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var body errorResponse
+				decode(t, w, &body)
+				if body.Error == "" {
+					t.Fatal("got no error message")
+				}
+			}
+		})
+	}
+}
+
+func TestListAndGetGames(t *testing.T) {
+	h, _ := newTestHandler(t, "white")
+
+	var list struct {
+		Games []gameSummary `json:"games"`
+	}
+	decode(t, request(h, http.MethodGet, "/api/v1/games", testToken, ""), &list)
+	if len(list.Games) != 2 || list.Games[0].ID != "live" || list.Games[0].Status != statusActive {
+		t.Fatalf("got %+v, want the active game first", list.Games)
+	}
+	if done := list.Games[1]; done.ID != "done" || done.Status != statusFinished || done.Result != game.Loss || done.Moves != 4 || done.EndedAt == nil {
+		t.Fatalf("got %+v, want the finished game", done)
+	}
+
+	var live gameDetail
+	decode(t, request(h, http.MethodGet, "/api/v1/games/live", testToken, ""), &live)
+	if live.Turn != game.White || live.Players[game.White] != "U1" || live.Timers == nil || live.Timers.VoteDeadline != nil {
+		t.Fatalf("got %+v, want White to move without a vote deadline", live)
+	}
+
+	var done gameDetail
+	decode(t, request(h, http.MethodGet, "/api/v1/games/done", testToken, ""), &done)
+	if done.FEN != "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3" || done.Method != "Checkmate" || done.Players[game.Black] != "chessbot" || done.Timers != nil {
+		t.Fatalf("got %+v, want the final position of the finished game", done)
+	}
+}
+
+func TestPostVote(t *testing.T) {
+	tests := []struct {
+		name       string
+		humanColor string
+		path       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"finished game", "white", "/api/v1/games/done/votes", `{"player_id":"bob","move":"e4"}`, http.StatusConflict, "the game is already over"},
+		{"missing game", "white", "/api/v1/games/missing/votes", `{"player_id":"bob","move":"e4"}`, http.StatusNotFound, "there is no active game with ID missing"},
+		{"not JSON", "white", "/api/v1/games/live/votes", `e4`, http.StatusBadRequest, "the body must be a JSON object with player_id and move"},
+		{"no move", "white", "/api/v1/games/live/votes", `{"player_id":"bob"}`, http.StatusBadRequest, "player_id and move are required"},
+		{"bad player ID", "white", "/api/v1/games/live/votes", `{"player_id":"<@U1>","move":"e4"}`, http.StatusBadRequest, "player_id must be 1 to 20 letters, digits, _ or -"},
+		{"illegal move", "white", "/api/v1/games/live/votes", `{"player_id":"bob","move":"e5"}`, http.StatusUnprocessableEntity, "e5 is not a valid move"},
+		{"bot's turn", "black", "/api/v1/games/live/votes", `{"player_id":"bob","move":"e4"}`, http.StatusConflict, "it is the bot's turn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, gm := newTestHandler(t, tt.humanColor)
+			w := request(h, http.MethodPost, tt.path, testToken, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var body errorResponse
+			decode(t, w, &body)
+			if body.Error != tt.wantError {
+				t.Fatalf("got the error %q, want %q", body.Error, tt.wantError)
+			}
+			if votes := gm.Votes(); len(votes) != 0 {
+				t.Fatalf("got the votes %v, want none", votes)
+			}
+		})
+	}
+}
+
+func TestVotesArePrefixed(t *testing.T) {
+	h, gm := newTestHandler(t, "white")
+	if err := gm.Vote("U1", "d4"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token holder naming themselves after a channel member votes apart from them
+	w := request(h, http.MethodPost, "/api/v1/games/live/votes", testToken, `{"player_id":"U1","move":"e4"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body.String())
+	}
+	var body votesResponse
+	decode(t, w, &body)
+	if body.ID != "live" || body.Status != statusActive || len(body.Votes) != 2 {
+		t.Fatalf("got %+v, want both votes of the active game", body)
+	}
+
+	if votes := gm.Votes(); votes["U1"] != "d4" || votes["api:U1"] != "e4" {
+		t.Fatalf("got the votes %v, want the API vote as api:U1", votes)
+	}
+
+	var votes votesResponse
+	decode(t, request(h, http.MethodGet, "/api/v1/games/live/votes", testToken, ""), &votes)
+	for _, vc := range votes.Votes {
+		if vc.Move == "e4" && (len(vc.Players) != 1 || vc.Players[0] != "api:U1") {
+			t.Fatalf("got %+v, want e4 voted by api:U1", vc)
+		}
+	}
+}
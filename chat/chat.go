@@ -0,0 +1,51 @@
+// Package chat describes what the bot needs from a chat platform so that the game logic
+// doesn't depend on any platform in particular.
+//
+// Texts are written in Slack's mrkdwn (*bold*, <@U123> mentions, :emoji: shortcodes),
+// clients of other platforms translate it to whatever their platform understands.
+package chat
+
+// Message is a message the bot received
+type Message struct {
+	ChannelID string
+	// ThreadID is the thread the message was posted in, empty outside of threads
+	ThreadID string
+	// Timestamp identifies the message within its channel
+	Timestamp string
+	UserID    string
+	Text      string
+	// Direct is set for private messages to the bot
+	Direct bool
+	// Mention is set when the message is addressed to the bot
+	Mention bool
+}
+
+// Image is a picture posted along with a text, it is either uploaded from Data or linked from URL
+type Image struct {
+	// Name is the file name of uploaded images, like board.png
+	Name string
+	Data []byte
+	URL  string
+	// Color is an accent color shown next to the image by the platforms that support it, like #eeeeee
+	Color string
+	// Alt stands in for the image on platforms that can only show text, like a text board
+	Alt string
+}
+
+// Client posts messages to a chat platform
+type Client interface {
+	// PostText posts a text to a channel
+	PostText(channelID string, text string) error
+	// PostImage posts a text to a channel along with an image
+	PostImage(channelID string, text string, image Image) error
+	// PostEphemeral posts a text to a channel that only the given user can see.
+	// Platforms without such messages send it privately instead
+	PostEphemeral(channelID string, userID string, text string) error
+	// PostThreadReply posts a text as a reply in a thread of a channel
+	PostThreadReply(channelID string, threadID string, text string) error
+}
+
+// Handler handles the messages received from a chat platform
+type Handler interface {
+	HandleMessage(m Message)
+}
@@ -0,0 +1,92 @@
+// Package chattest provides an in-memory chat platform to drive the bot without a real one.
+package chattest
+
+import (
+	"sync"
+
+	"github.com/dyslexicat/collab-chess/chat"
+)
+
+// Post is a message the bot posted to the fake platform
+type Post struct {
+	ChannelID string
+	// ThreadID is set for thread replies
+	ThreadID string
+	// UserID is set for ephemeral messages
+	UserID string
+	Text   string
+	Image  *chat.Image
+}
+
+// Fake records everything posted to it and delivers messages to a handler as if they were sent on a platform
+type Fake struct {
+	Handler chat.Handler
+
+	posts  []Post
+	notify chan struct{}
+	sync.Mutex
+}
+
+// NewFake returns a fake platform delivering messages to the handler
+func NewFake(handler chat.Handler) *Fake {
+	return &Fake{Handler: handler, notify: make(chan struct{}, 1)}
+}
+
+// Send delivers a message to the handler as if it was sent by a user
+func (f *Fake) Send(m chat.Message) {
+	f.Handler.HandleMessage(m)
+}
+
+// Posts returns everything posted so far
+func (f *Fake) Posts() []Post {
+	f.Lock()
+	defer f.Unlock()
+	posts := make([]Post, len(f.posts))
+	copy(posts, f.posts)
+	return posts
+}
+
+// Updated receives a value whenever something was posted since the last receive,
+// which lets callers wait for the bot without polling
+func (f *Fake) Updated() <-chan struct{} {
+	return f.notify
+}
+
+// Reset forgets everything posted so far
+func (f *Fake) Reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.posts = nil
+}
+
+func (f *Fake) record(post Post) error {
+	f.Lock()
+	f.posts = append(f.posts, post)
+	f.Unlock()
+
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// PostText records a text posted to a channel
+func (f *Fake) PostText(channelID string, text string) error {
+	return f.record(Post{ChannelID: channelID, Text: text})
+}
+
+// PostImage records a text posted to a channel along with an image
+func (f *Fake) PostImage(channelID string, text string, image chat.Image) error {
+	return f.record(Post{ChannelID: channelID, Text: text, Image: &image})
+}
+
+// PostEphemeral records a text posted to a channel for a single user
+func (f *Fake) PostEphemeral(channelID string, userID string, text string) error {
+	return f.record(Post{ChannelID: channelID, UserID: userID, Text: text})
+}
+
+// PostThreadReply records a text posted in a thread
+func (f *Fake) PostThreadReply(channelID string, threadID string, text string) error {
+	return f.record(Post{ChannelID: channelID, ThreadID: threadID, Text: text})
+}
@@ -0,0 +1,138 @@
+// Package discordchat connects the bot to Discord through the gateway.
+package discordchat
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/chat"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// intents are the gateway events the bot listens to, reading commands needs the message content
+const intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentMessageContent
+
+var (
+	// boldRegex finds Slack's *bold* which is **bold** on Discord
+	boldRegex = regexp.MustCompile(`\*([^*\n]+)\*`)
+	// nicknameMentionRegex finds Discord's <@!123> mentions which are <@123> everywhere else
+	nicknameMentionRegex = regexp.MustCompile(`<@!(\d+)>`)
+)
+
+// Client posts messages to Discord and hands the messages it receives to a handler
+type Client struct {
+	session *discordgo.Session
+}
+
+// UseEndpoint points every Discord session at another API, like a local fake gateway.
+// discordgo keeps its endpoints in package variables so this has to happen before any session is opened
+func UseEndpoint(apiURL string) {
+	apiURL = strings.TrimSuffix(apiURL, "/") + "/"
+	discordgo.EndpointAPI = apiURL
+	discordgo.EndpointGuilds = apiURL + "guilds/"
+	discordgo.EndpointChannels = apiURL + "channels/"
+	discordgo.EndpointUsers = apiURL + "users/"
+	discordgo.EndpointGateway = apiURL + "gateway"
+	discordgo.EndpointGatewayBot = discordgo.EndpointGateway + "/bot"
+}
+
+// New returns a Client for the bot token, it doesn't connect until Open is called
+func New(botToken string) (*Client, error) {
+	session, err := discordgo.New("Bot " + botToken)
+	if err != nil {
+		return nil, err
+	}
+	session.Identify.Intents = intents
+	return &Client{session: session}, nil
+}
+
+// Open connects to the gateway and hands every message of other users to the handler
+func (c *Client) Open(handler chat.Handler) error {
+	c.session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		// ignore ourselves and other bots
+		if m.Author == nil || m.Author.Bot {
+			return
+		}
+		handler.HandleMessage(c.message(m))
+	})
+	return c.session.Open()
+}
+
+// Close disconnects from the gateway
+func (c *Client) Close() error {
+	return c.session.Close()
+}
+
+// message converts a Discord message to a chat message
+func (c *Client) message(m *discordgo.MessageCreate) chat.Message {
+	text := nicknameMentionRegex.ReplaceAllString(strings.TrimSpace(m.Content), "<@$1>")
+
+	msg := chat.Message{
+		ChannelID: m.ChannelID,
+		Timestamp: m.ID,
+		UserID:    m.Author.ID,
+		Text:      text,
+		// messages outside of servers are direct messages
+		Direct: m.GuildID == "",
+	}
+
+	// commands that happen to mention the bot, like !stats @chessbot, are still commands
+	if c.session.State != nil && c.session.State.User != nil && !strings.HasPrefix(text, "!") {
+		for _, user := range m.Mentions {
+			if user.ID == c.session.State.User.ID {
+				msg.Mention = true
+			}
+		}
+	}
+	return msg
+}
+
+// PostText posts a text to a channel
+func (c *Client) PostText(channelID string, text string) error {
+	_, err := c.session.ChannelMessageSend(channelID, format(text))
+	return err
+}
+
+// PostImage posts a text along with the image as an embed, uploaded images are attached to the message
+func (c *Client) PostImage(channelID string, text string, image chat.Image) error {
+	embed := &discordgo.MessageEmbed{Image: &discordgo.MessageEmbedImage{URL: image.URL}}
+	if color, err := strconv.ParseInt(strings.TrimPrefix(image.Color, "#"), 16, 32); err == nil {
+		embed.Color = int(color)
+	}
+
+	message := &discordgo.MessageSend{
+		Content: format(text),
+		Embeds:  []*discordgo.MessageEmbed{embed},
+	}
+	if image.Data != nil {
+		embed.Image.URL = "attachment://" + image.Name
+		message.Files = []*discordgo.File{{Name: image.Name, Reader: bytes.NewReader(image.Data)}}
+	}
+
+	_, err := c.session.ChannelMessageSendComplex(channelID, message)
+	return err
+}
+
+// PostEphemeral sends the text as a direct message since Discord only has ephemeral messages for interactions
+func (c *Client) PostEphemeral(channelID string, userID string, text string) error {
+	dm, err := c.session.UserChannelCreate(userID)
+	if err != nil {
+		log.Println("could not open a direct message channel with", userID, err)
+		return err
+	}
+	return c.PostText(dm.ID, text)
+}
+
+// PostThreadReply posts a text in a thread, threads are channels of their own on Discord
+func (c *Client) PostThreadReply(channelID string, threadID string, text string) error {
+	return c.PostText(threadID, text)
+}
+
+// format translates Slack's mrkdwn to Discord's markdown
+func format(text string) string {
+	return chat.ReplaceEmoji(boldRegex.ReplaceAllString(text, "**$1**"))
+}
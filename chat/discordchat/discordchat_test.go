@@ -0,0 +1,107 @@
+package discordchat_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat/discordchat"
+	"github.com/dyslexicat/collab-chess/chat/discordchat/discordtest"
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/handler"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	guildID   = "g1"
+	channelID = "c1"
+)
+
+// firstMoveEngine plays the first legal move so games don't need Stockfish
+type firstMoveEngine struct{}
+
+func (firstMoveEngine) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	return position.ValidMoves()[0], game.Evaluation{}, nil
+}
+
+func (firstMoveEngine) Close() error {
+	return nil
+}
+
+// waitForPost waits until the bot posted something in the channel containing text
+func waitForPost(t *testing.T, gateway *discordtest.Gateway, channelID string, text string) discordtest.Post {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, post := range gateway.Posts() {
+			if post.ChannelID == channelID && strings.Contains(post.Content, text) {
+				return post
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the bot did not post %q in %s, posts: %+v", text, channelID, gateway.Posts())
+	return discordtest.Post{}
+}
+
+func TestGameRoundTripsThroughTheGateway(t *testing.T) {
+	gateway := discordtest.NewGateway()
+	defer gateway.Close()
+	discordchat.UseEndpoint(gateway.URL())
+
+	client, err := discordchat.New("test-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := handler.Bot{
+		Chat:         client,
+		GameStorage:  game.NewMemoryStore(),
+		LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key"),
+		GameChannel:  channelID,
+		Settings:     handler.NewChannelSettings(),
+		NewEngine: func() (handler.Engine, error) {
+			return firstMoveEngine{}, nil
+		},
+		VoteDuration: 100 * time.Millisecond,
+	}
+	if err := client.Open(bot); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if !gateway.Connected() {
+		t.Fatal("the bot did not identify on the gateway")
+	}
+
+	if err := gateway.Send(guildID, channelID, "u1", "!start white"); err != nil {
+		t.Fatal(err)
+	}
+	waitForPost(t, gateway, channelID, "Hackalackers are playing: White")
+
+	// invalid moves are told to the voter alone, in a direct message
+	if err := gateway.Send(guildID, channelID, "u2", "!move e5"); err != nil {
+		t.Fatal(err)
+	}
+	waitForPost(t, gateway, "dm-u2", "**e5** is not a valid move right now")
+
+	if err := gateway.Send(guildID, channelID, "u1", "!move e4"); err != nil {
+		t.Fatal(err)
+	}
+	waitForPost(t, gateway, channelID, "Top voted move was: **e4**")
+	post := waitForPost(t, gateway, channelID, "I made my move ⚔️")
+	if post.ImageURL == "" {
+		t.Fatalf("got %+v, want the board as an embedded image", post)
+	}
+
+	// commands are not answered in direct messages
+	if err := gateway.Send("", "dm-u1", "u1", "!board"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, post := range gateway.Posts() {
+		if post.ChannelID == "dm-u1" {
+			t.Fatalf("got %+v, want !board in a DM to be ignored", post)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package discordtest runs a local stand-in for Discord's gateway and REST API
+// so the Discord front-end can be driven without connecting to Discord.
+package discordtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// BotID is the user ID of the bot on the fake gateway
+const BotID = "1000"
+
+// Post is a message the bot posted through the REST API
+type Post struct {
+	ChannelID string
+	Content   string
+	// ImageURL is the image of the first embed, if any
+	ImageURL string
+	// Files are the names of the attached files
+	Files []string
+}
+
+// Gateway is a fake Discord with a gateway websocket and the REST endpoints the bot uses
+type Gateway struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	conn     *websocket.Conn
+	sequence int
+	posts    []Post
+	nextID   int
+	sync.Mutex
+}
+
+// gatewayPayload is a message on the gateway websocket
+type gatewayPayload struct {
+	Op       int         `json:"op"`
+	Data     interface{} `json:"d"`
+	Sequence int         `json:"s,omitempty"`
+	Type     string      `json:"t,omitempty"`
+}
+
+// NewGateway starts a fake Discord, point the bot at it with discordchat.UseEndpoint(g.URL())
+func NewGateway() *Gateway {
+	g := &Gateway{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/gateway", g.serveGatewayURL)
+	mux.HandleFunc("/api/gateway/bot", g.serveGatewayURL)
+	// discordgo adds a trailing slash to the gateway URL
+	mux.HandleFunc("/ws/", g.serveWebsocket)
+	mux.HandleFunc("/api/channels/", g.serveChannelMessages)
+	mux.HandleFunc("/api/users/@me/channels", g.serveDMChannel)
+	g.server = httptest.NewServer(mux)
+	return g
+}
+
+// URL returns the base URL of the fake REST API
+func (g *Gateway) URL() string {
+	return g.server.URL + "/api/"
+}
+
+// Close shuts the fake Discord down
+func (g *Gateway) Close() {
+	g.Lock()
+	if g.conn != nil {
+		g.conn.Close()
+	}
+	g.Unlock()
+	g.server.Close()
+}
+
+// Connected tells whether the bot has identified itself on the gateway
+func (g *Gateway) Connected() bool {
+	g.Lock()
+	defer g.Unlock()
+	return g.conn != nil
+}
+
+// Send dispatches a message of a user in a channel to the bot. An empty guildID sends a direct message
+func (g *Gateway) Send(guildID, channelID, userID, content string) error {
+	g.Lock()
+	defer g.Unlock()
+	if g.conn == nil {
+		return fmt.Errorf("the bot is not connected")
+	}
+
+	g.sequence++
+	g.nextID++
+	return g.conn.WriteJSON(gatewayPayload{
+		Op:       0,
+		Type:     "MESSAGE_CREATE",
+		Sequence: g.sequence,
+		Data: map[string]interface{}{
+			"id":         fmt.Sprintf("m%d", g.nextID),
+			"channel_id": channelID,
+			"guild_id":   guildID,
+			"content":    content,
+			"author":     map[string]interface{}{"id": userID, "username": "user" + userID},
+			"mentions":   []interface{}{},
+		},
+	})
+}
+
+// Posts returns everything the bot posted so far
+func (g *Gateway) Posts() []Post {
+	g.Lock()
+	defer g.Unlock()
+	posts := make([]Post, len(g.posts))
+	copy(posts, g.posts)
+	return posts
+}
+
+func (g *Gateway) serveGatewayURL(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"url":    "ws" + strings.TrimPrefix(g.server.URL, "http") + "/ws",
+		"shards": 1,
+	})
+}
+
+// serveWebsocket says hello, waits for the identify and answers with READY, then acknowledges heartbeats
+func (g *Gateway) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(gatewayPayload{Op: 10, Data: map[string]int{"heartbeat_interval": 45000}}); err != nil {
+		return
+	}
+
+	var identify gatewayPayload
+	if err := conn.ReadJSON(&identify); err != nil || identify.Op != 2 {
+		return
+	}
+
+	g.Lock()
+	g.sequence++
+	err = conn.WriteJSON(gatewayPayload{
+		Op:       0,
+		Type:     "READY",
+		Sequence: g.sequence,
+		Data: map[string]interface{}{
+			"v":          9,
+			"session_id": "fake-session",
+			"user":       map[string]interface{}{"id": BotID, "username": "chessbot", "bot": true},
+			"guilds":     []interface{}{},
+		},
+	})
+	g.conn = conn
+	g.Unlock()
+	if err != nil {
+		return
+	}
+
+	for {
+		var payload gatewayPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			g.Lock()
+			if g.conn == conn {
+				g.conn = nil
+			}
+			g.Unlock()
+			return
+		}
+		// heartbeats are acknowledged, everything else is ignored
+		if payload.Op == 1 {
+			g.Lock()
+			conn.WriteJSON(gatewayPayload{Op: 11})
+			g.Unlock()
+		}
+	}
+}
+
+// messageBody is the part of a posted message the fake keeps
+type messageBody struct {
+	Content string `json:"content"`
+	Embeds  []struct {
+		Image *struct {
+			URL string `json:"url"`
+		} `json:"image"`
+	} `json:"embeds"`
+}
+
+// serveChannelMessages records messages posted as JSON or as multipart with attached files
+func (g *Gateway) serveChannelMessages(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/channels/")
+	parts := strings.Split(path, "/")
+	if r.Method != http.MethodPost || len(parts) != 2 || parts[1] != "messages" {
+		http.NotFound(w, r)
+		return
+	}
+	post := Post{ChannelID: parts[0]}
+
+	var body messageBody
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := ioutil.ReadAll(part)
+			if part.FormName() == "payload_json" {
+				json.Unmarshal(data, &body)
+			} else if part.FileName() != "" {
+				post.Files = append(post.Files, part.FileName())
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post.Content = body.Content
+	if len(body.Embeds) > 0 && body.Embeds[0].Image != nil {
+		post.ImageURL = body.Embeds[0].Image.URL
+	}
+
+	g.Lock()
+	g.posts = append(g.posts, post)
+	g.nextID++
+	id := fmt.Sprintf("m%d", g.nextID)
+	g.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"id":         id,
+		"channel_id": post.ChannelID,
+		"content":    post.Content,
+		"author":     map[string]interface{}{"id": BotID, "bot": true},
+	})
+}
+
+// serveDMChannel opens a direct message channel, its ID is dm- followed by the user ID
+func (g *Gateway) serveDMChannel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RecipientID string `json:"recipient_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"id": "dm-" + body.RecipientID, "type": 1})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
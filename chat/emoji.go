@@ -0,0 +1,27 @@
+package chat
+
+import "regexp"
+
+// Emoji maps the Slack emoji shortcodes the bot uses to their Unicode characters
+// for the platforms that don't understand shortcodes
+var Emoji = map[string]string{
+	"chess_pawn":             "♟️",
+	"clap":                   "👏",
+	"crossed_swords":         "⚔️",
+	"film_projector":         "📽️",
+	"hourglass_flowing_sand": "⏳",
+	"mag":                    "🔍",
+	"trophy":                 "🏆",
+}
+
+var shortcodeRegex = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// ReplaceEmoji replaces the known emoji shortcodes of a text with their Unicode characters
+func ReplaceEmoji(text string) string {
+	return shortcodeRegex.ReplaceAllStringFunc(text, func(code string) string {
+		if emoji, ok := Emoji[code[1:len(code)-1]]; ok {
+			return emoji
+		}
+		return code
+	})
+}
@@ -0,0 +1,345 @@
+// Package ircchat connects the bot to an IRC channel.
+package ircchat
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dyslexicat/collab-chess/chat"
+)
+
+const (
+	// DefaultFloodBurst and DefaultFloodInterval follow the flood control of RFC 1459:
+	// every line costs two seconds and the client may run ten seconds ahead
+	DefaultFloodBurst    = 5
+	DefaultFloodInterval = 2 * time.Second
+	// maxLineLength leaves room for the prefix the server adds when relaying a line within the 512 bytes limit
+	maxLineLength = 400
+	// queueSize is how many lines wait for the flood limit before new ones are dropped
+	queueSize = 200
+	// registerTimeout is how long the server has to welcome the bot
+	registerTimeout = 30 * time.Second
+)
+
+var (
+	// boldRegex finds Slack's *bold* which is wrapped in the IRC bold control code
+	boldRegex = regexp.MustCompile(`\*([^*\n]+)\*`)
+	// mentionRegex finds Slack's <@U123> and <@U123|name> mentions, IRC users are just nicks
+	mentionRegex = regexp.MustCompile(`<@([^>|]+)(?:\|([^>]*))?>`)
+)
+
+// Config tells the client where and as whom to connect
+type Config struct {
+	// Server is the host:port of the IRC server
+	Server   string
+	TLS      bool
+	Nick     string
+	Password string
+	// Channel is joined once the server welcomes the bot, like #chess
+	Channel string
+	// FloodBurst is how many lines are sent at once before FloodInterval is waited between lines
+	FloodBurst    int
+	FloodInterval time.Duration
+}
+
+// Client posts messages to an IRC channel and hands the messages it receives to a handler
+type Client struct {
+	config Config
+	nick   string
+
+	conn    net.Conn
+	writeMu sync.Mutex
+	queue   chan string
+	welcome chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// New returns a Client for the config, it doesn't connect until Open is called
+func New(config Config) *Client {
+	if config.FloodBurst <= 0 {
+		config.FloodBurst = DefaultFloodBurst
+	}
+	if config.FloodInterval <= 0 {
+		config.FloodInterval = DefaultFloodInterval
+	}
+	return &Client{
+		config:  config,
+		nick:    config.Nick,
+		queue:   make(chan string, queueSize),
+		welcome: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Open connects to the server, registers, joins the channel and hands every message to the handler
+func (c *Client) Open(handler chat.Handler) error {
+	var err error
+	if c.config.TLS {
+		c.conn, err = tls.Dial("tcp", c.config.Server, &tls.Config{})
+	} else {
+		c.conn, err = net.Dial("tcp", c.config.Server)
+	}
+	if err != nil {
+		return err
+	}
+
+	go c.read(handler)
+	go c.write()
+
+	if c.config.Password != "" {
+		c.send("PASS " + c.config.Password)
+	}
+	c.send("NICK " + c.nick)
+	c.send(fmt.Sprintf("USER %s 0 * :collab-chess", c.config.Nick))
+
+	select {
+	case <-c.welcome:
+	case <-c.done:
+		return fmt.Errorf("the connection to %s was closed during registration", c.config.Server)
+	case <-time.After(registerTimeout):
+		c.stop()
+		c.conn.Close()
+		return fmt.Errorf("%s didn't welcome us in %v", c.config.Server, registerTimeout)
+	}
+
+	c.send("JOIN " + c.config.Channel)
+	return nil
+}
+
+// Close leaves the server
+func (c *Client) Close() error {
+	c.send("QUIT :bye")
+	c.stop()
+	return c.conn.Close()
+}
+
+// stop tells the writer that the connection is gone
+func (c *Client) stop() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// send writes a line right away, bypassing the flood limit. Only used for registration and PONG
+func (c *Client) send(line string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(time.Minute))
+	_, err := fmt.Fprintf(c.conn, "%s\r\n", line)
+	return err
+}
+
+// enqueue queues a line to be sent within the flood limit
+func (c *Client) enqueue(line string) error {
+	select {
+	case c.queue <- line:
+		return nil
+	default:
+		return fmt.Errorf("too many lines waiting for the flood limit, dropped %q", line)
+	}
+}
+
+// write sends the queued lines following the flood control of RFC 1459: a timer moves
+// FloodInterval ahead with every line and lines wait while it is FloodBurst lines ahead of the clock
+func (c *Client) write() {
+	var timer time.Time
+	for {
+		select {
+		case <-c.done:
+			return
+		case line := <-c.queue:
+			now := time.Now()
+			if timer.Before(now) {
+				timer = now
+			}
+			if wait := timer.Sub(now) - time.Duration(c.config.FloodBurst-1)*c.config.FloodInterval; wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-c.done:
+					return
+				}
+			}
+			if err := c.send(line); err != nil {
+				log.Println("could not write to", c.config.Server, err)
+				return
+			}
+			timer = timer.Add(c.config.FloodInterval)
+		}
+	}
+}
+
+// read handles the lines from the server until the connection is closed
+func (c *Client) read(handler chat.Handler) {
+	defer c.stop()
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		prefix, command, params := parseLine(scanner.Text())
+		switch command {
+		case "PING":
+			c.send("PONG :" + strings.Join(params, " "))
+		case "001":
+			select {
+			case <-c.welcome:
+			default:
+				close(c.welcome)
+			}
+		case "433":
+			// the nick is taken, try another one
+			c.nick += "_"
+			c.send("NICK " + c.nick)
+		case "PRIVMSG":
+			if len(params) < 2 {
+				continue
+			}
+			if msg, ok := c.message(prefix, params[0], params[1]); ok {
+				handler.HandleMessage(msg)
+			}
+		}
+	}
+	log.Println("disconnected from", c.config.Server)
+}
+
+// message converts a PRIVMSG to a chat message, direct messages are answered in private
+func (c *Client) message(prefix, target, text string) (chat.Message, bool) {
+	nick := strings.SplitN(prefix, "!", 2)[0]
+	// CTCP requests like ACTION or VERSION aren't commands
+	if nick == "" || strings.HasPrefix(text, "\x01") {
+		return chat.Message{}, false
+	}
+
+	msg := chat.Message{ChannelID: target, UserID: nick, Text: strings.TrimSpace(text)}
+	if !strings.HasPrefix(target, "#") && !strings.HasPrefix(target, "&") {
+		msg.ChannelID = nick
+		msg.Direct = true
+	}
+
+	// chessbot: hello
+	lower := strings.ToLower(msg.Text)
+	for _, sep := range []string{":", ","} {
+		if strings.HasPrefix(lower, strings.ToLower(c.nick)+sep) {
+			msg.Mention = true
+		}
+	}
+	return msg, true
+}
+
+// parseLine splits a line like ":nick!user@host PRIVMSG #chess :!move e4" into its prefix, command and parameters
+func parseLine(line string) (prefix string, command string, params []string) {
+	if strings.HasPrefix(line, ":") {
+		parts := strings.SplitN(line[1:], " ", 2)
+		prefix = parts[0]
+		if len(parts) == 1 {
+			return prefix, "", nil
+		}
+		line = parts[1]
+	}
+
+	var trailing *string
+	if i := strings.Index(line, " :"); i >= 0 {
+		rest := line[i+2:]
+		trailing = &rest
+		line = line[:i]
+	} else if strings.HasPrefix(line, ":") {
+		rest := line[1:]
+		trailing = &rest
+		line = ""
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		command = strings.ToUpper(fields[0])
+		params = fields[1:]
+	}
+	if trailing != nil {
+		params = append(params, *trailing)
+	}
+	return prefix, command, params
+}
+
+// PostText posts a text to a channel, every line of it as a PRIVMSG of its own
+func (c *Client) PostText(channelID string, text string) error {
+	return c.post("PRIVMSG", channelID, text)
+}
+
+// PostImage posts the text followed by the alternative text of the image, like a text board, and its link.
+// Uploaded images can't be shown on IRC
+func (c *Client) PostImage(channelID string, text string, image chat.Image) error {
+	lines := []string{text}
+	if image.Alt != "" {
+		lines = append(lines, image.Alt)
+	}
+	// relative links, when there is no public hostname, are of no use outside the server
+	if strings.HasPrefix(image.URL, "http://") || strings.HasPrefix(image.URL, "https://") {
+		lines = append(lines, image.URL)
+	}
+	return c.post("PRIVMSG", channelID, strings.Join(lines, "\n"))
+}
+
+// PostEphemeral sends the text as a NOTICE to the user
+func (c *Client) PostEphemeral(channelID string, userID string, text string) error {
+	return c.post("NOTICE", userID, text)
+}
+
+// PostThreadReply posts the text to the channel since IRC has no threads
+func (c *Client) PostThreadReply(channelID string, threadID string, text string) error {
+	return c.PostText(channelID, text)
+}
+
+func (c *Client) post(command, target, text string) error {
+	for _, line := range format(text) {
+		if err := c.enqueue(fmt.Sprintf("%s %s :%s", command, target, line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// format translates Slack's mrkdwn to IRC lines that fit the line length limit
+func format(text string) []string {
+	text = strings.ReplaceAll(text, "```", "")
+	text = boldRegex.ReplaceAllString(text, "\x02$1\x02")
+	text = mentionRegex.ReplaceAllStringFunc(text, func(mention string) string {
+		matches := mentionRegex.FindStringSubmatch(mention)
+		if matches[2] != "" {
+			return matches[2]
+		}
+		return matches[1]
+	})
+	text = chat.ReplaceEmoji(text)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// IRC drops empty messages
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, split(line, maxLineLength)...)
+	}
+	return lines
+}
+
+// split breaks a line into parts of at most max bytes, preferably at spaces and never within a character
+func split(line string, max int) []string {
+	var parts []string
+	for len(line) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if space := strings.LastIndex(line[:cut], " "); space > 0 {
+			cut = space
+		}
+		parts = append(parts, line[:cut])
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	return append(parts, line)
+}
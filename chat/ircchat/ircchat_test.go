@@ -0,0 +1,271 @@
+package ircchat_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dyslexicat/collab-chess/chat"
+	"github.com/dyslexicat/collab-chess/chat/ircchat"
+	"github.com/dyslexicat/collab-chess/chat/ircchat/irctest"
+)
+
+const channel = "#chess"
+
+// recorder keeps every message the client hands to the bot
+type recorder struct {
+	mu       sync.Mutex
+	messages []chat.Message
+}
+
+func (r *recorder) HandleMessage(m chat.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, m)
+}
+
+func (r *recorder) Messages() []chat.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]chat.Message(nil), r.messages...)
+}
+
+// connect starts a server and connects a client to it with the given flood limit
+func connect(t *testing.T, burst int, interval time.Duration) (*irctest.Server, *ircchat.Client, *recorder) {
+	t.Helper()
+	server, err := irctest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	client := ircchat.New(ircchat.Config{
+		Server:        server.Addr(),
+		Nick:          "chessbot",
+		Channel:       channel,
+		FloodBurst:    burst,
+		FloodInterval: interval,
+	})
+	handler := &recorder{}
+	if err := client.Open(handler); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	waitForLine(t, server, "JOIN "+channel)
+	return server, client, handler
+}
+
+// waitForLine waits until a client sent the line and returns it
+func waitForLine(t *testing.T, server *irctest.Server, text string) irctest.Line {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, line := range server.Lines() {
+			if line.Text == text {
+				return line
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%q was never sent, lines: %+v", text, server.Lines())
+	return irctest.Line{}
+}
+
+// waitForMessages waits until n PRIVMSG or NOTICE lines were sent to the target and returns their lines
+func waitForMessages(t *testing.T, server *irctest.Server, target string, n int) []irctest.Line {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var lines []irctest.Line
+		for _, line := range server.Lines() {
+			if strings.HasPrefix(line.Text, "PRIVMSG "+target+" :") || strings.HasPrefix(line.Text, "NOTICE "+target+" :") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) >= n {
+			return lines
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("got messages %q, want %d", server.Messages(target), n)
+	return nil
+}
+
+func TestLinesWaitForTheFloodLimit(t *testing.T) {
+	const burst = 3
+	const interval = 150 * time.Millisecond
+	server, client, _ := connect(t, burst, interval)
+
+	if err := client.PostText(channel, "one\ntwo\nthree\nfour\nfive\nsix"); err != nil {
+		t.Fatal(err)
+	}
+	lines := waitForMessages(t, server, channel, 6)
+
+	for i, want := range []string{"one", "two", "three", "four", "five", "six"} {
+		if got := lines[i].Text; got != "PRIVMSG "+channel+" :"+want {
+			t.Fatalf("line %d is %q, want the lines in order", i, got)
+		}
+	}
+	// the burst goes out at once
+	if gap := lines[burst-1].At.Sub(lines[0].At); gap > interval/2 {
+		t.Fatalf("the first %d lines took %v, want them sent at once", burst, gap)
+	}
+	// after that a line goes out every interval
+	for i := burst; i < len(lines); i++ {
+		if gap := lines[i].At.Sub(lines[i-1].At); gap < interval*3/4 {
+			t.Fatalf("line %d arrived %v after the one before, want about %v", i, gap, interval)
+		}
+	}
+	if total := lines[len(lines)-1].At.Sub(lines[0].At); total < time.Duration(len(lines)-burst)*interval*3/4 {
+		t.Fatalf("the lines took %v, want at least %v", total, time.Duration(len(lines)-burst)*interval)
+	}
+}
+
+func TestLongLinesAreSplit(t *testing.T) {
+	server, client, _ := connect(t, 10, time.Millisecond)
+
+	words := strings.Repeat("pawn ", 199) + "pawn"
+	// no spaces to split at and two bytes per character
+	accents := strings.Repeat("é", 300)
+	if err := client.PostText(channel, "*"+words+"*\n\n"+accents); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := waitForMessages(t, server, channel, 5)
+	var parts []string
+	for _, line := range lines {
+		part := strings.TrimPrefix(line.Text, "PRIVMSG "+channel+" :")
+		if len(part) > 400 {
+			t.Fatalf("got a %d byte line, want at most 400", len(part))
+		}
+		if !utf8.ValidString(part) {
+			t.Fatalf("got %q, want lines split between characters", part)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) != 5 {
+		t.Fatalf("got %d lines, want 3 for the words and 2 for the accents: %q", len(parts), parts)
+	}
+
+	joined := strings.Join(parts[:3], " ")
+	if joined != "\x02"+words+"\x02" {
+		t.Fatalf("got %q, want the words back in bold when joined at the splits", joined)
+	}
+	for _, part := range parts[:3] {
+		if strings.HasPrefix(part, " ") || strings.HasSuffix(part, " ") {
+			t.Fatalf("got %q, want the words split at spaces", part)
+		}
+	}
+	if parts[3] != strings.Repeat("é", 200) || parts[4] != strings.Repeat("é", 100) {
+		t.Fatalf("got %q and %q, want 200 and 100 characters", parts[3], parts[4])
+	}
+}
+
+func TestTakenNicksAreRetried(t *testing.T) {
+	server, _, _ := connect(t, 10, time.Millisecond)
+
+	second := ircchat.New(ircchat.Config{Server: server.Addr(), Nick: "chessbot", Channel: channel})
+	handler := &recorder{}
+	if err := second.Open(handler); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if line := waitForLine(t, server, "NICK chessbot_"); line.Nick != "" {
+		t.Fatalf("got the retry from %q, want it before the client had a nick", line.Nick)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !joined(server, "chessbot_") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !joined(server, "chessbot_") {
+		t.Fatalf("want the second client to join as chessbot_, lines: %+v", server.Lines())
+	}
+
+	// the new nick tells which messages are addressed to the bot
+	if err := server.Send("alice", "chessbot_", "chessbot_: !board"); err != nil {
+		t.Fatal(err)
+	}
+	for len(handler.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	want := chat.Message{ChannelID: "alice", UserID: "alice", Text: "chessbot_: !board", Direct: true, Mention: true}
+	if messages := handler.Messages(); len(messages) != 1 || messages[0] != want {
+		t.Fatalf("got %+v, want %+v", messages, want)
+	}
+}
+
+// joined tells if the nick joined the channel
+func joined(server *irctest.Server, nick string) bool {
+	for _, line := range server.Lines() {
+		if line.Text == "JOIN "+channel && line.Nick == nick {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCTCPRequestsAreNotHandled(t *testing.T) {
+	server, client, handler := connect(t, 10, time.Millisecond)
+
+	for _, text := range []string{"\x01VERSION\x01", "\x01ACTION moves e4\x01"} {
+		if err := server.Send("alice", channel, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := server.Send("alice", channel, "chessbot: !move e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Send("bob", "chessbot", "!board"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(handler.Messages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := handler.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("got %+v, want only the two messages that aren't CTCP", messages)
+	}
+	want := chat.Message{ChannelID: channel, UserID: "alice", Text: "chessbot: !move e4", Mention: true}
+	if messages[0] != want {
+		t.Fatalf("got %+v, want %+v", messages[0], want)
+	}
+	want = chat.Message{ChannelID: "bob", UserID: "bob", Text: "!board", Direct: true}
+	if messages[1] != want {
+		t.Fatalf("got %+v, want %+v", messages[1], want)
+	}
+
+	// replies to direct messages go back to the nick
+	if err := client.PostEphemeral(channel, "bob", "only for you"); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitForMessages(t, server, "bob", 1)[0].Text; got != "NOTICE bob :only for you" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestImagesArePostedAsTextAndAbsoluteLinks(t *testing.T) {
+	server, client, _ := connect(t, 10, time.Millisecond)
+
+	board := "8 ♜ ♞\n1 ♖ ♘"
+	if err := client.PostImage(channel, "*Top voted move was: e4*", chat.Image{URL: "/board.png?fen=x", Alt: board}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PostImage(channel, "I made my move", chat.Image{URL: "https://chess.example/board.png?fen=x", Alt: board}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForMessages(t, server, channel, 7)
+	want := []string{
+		"\x02Top voted move was: e4\x02", "8 ♜ ♞", "1 ♖ ♘",
+		// without a public hostname the link is relative and left out
+		"I made my move", "8 ♜ ♞", "1 ♖ ♘", "https://chess.example/board.png?fen=x",
+	}
+	if got := server.Messages(channel); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+// Package irctest runs a small in-process IRC server that is just enough to drive the IRC front-end.
+package irctest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Line is a line a client sent to the server along with when it arrived
+type Line struct {
+	Nick string
+	Text string
+	At   time.Time
+}
+
+// Server accepts clients, welcomes them, lets them join channels and records what they send
+type Server struct {
+	listener net.Listener
+
+	clients map[string]net.Conn
+	lines   []Line
+	sync.Mutex
+}
+
+// NewServer starts a server on a random local port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener, clients: make(map[string]net.Conn)}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and disconnects every client
+func (s *Server) Close() error {
+	s.Lock()
+	for _, conn := range s.clients {
+		conn.Close()
+	}
+	s.Unlock()
+	return s.listener.Close()
+}
+
+// Send delivers a PRIVMSG from the given nick to the target, a channel or the nick of a client
+func (s *Server) Send(from, target, text string) error {
+	line := fmt.Sprintf(":%s!%s@irctest PRIVMSG %s :%s\r\n", from, from, target, text)
+
+	s.Lock()
+	defer s.Unlock()
+	if len(s.clients) == 0 {
+		return fmt.Errorf("no client is connected")
+	}
+	for nick, conn := range s.clients {
+		if strings.HasPrefix(target, "#") || nick == target {
+			if _, err := conn.Write([]byte(line)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Lines returns everything the clients sent so far
+func (s *Server) Lines() []Line {
+	s.Lock()
+	defer s.Unlock()
+	lines := make([]Line, len(s.lines))
+	copy(lines, s.lines)
+	return lines
+}
+
+// Messages returns the text of the PRIVMSG and NOTICE lines sent to the target
+func (s *Server) Messages(target string) []string {
+	var messages []string
+	for _, line := range s.Lines() {
+		for _, command := range []string{"PRIVMSG ", "NOTICE "} {
+			prefix := command + target + " :"
+			if strings.HasPrefix(line.Text, prefix) {
+				messages = append(messages, strings.TrimPrefix(line.Text, prefix))
+			}
+		}
+	}
+	return messages
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve registers a client and answers the commands the bot uses. Like real servers it turns
+// away nicks that are in use with 433 and only welcomes clients once they have a nick
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var nick string
+	var user, welcomed bool
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		s.Lock()
+		s.lines = append(s.lines, Line{Nick: nick, Text: text, At: time.Now()})
+		s.Unlock()
+
+		switch strings.ToUpper(fields[0]) {
+		case "NICK":
+			if len(fields) < 2 {
+				continue
+			}
+			s.Lock()
+			if other, ok := s.clients[fields[1]]; ok && other != conn {
+				s.Unlock()
+				reply(":irctest 433 * %s :Nickname is already in use", fields[1])
+				continue
+			}
+			delete(s.clients, nick)
+			nick = fields[1]
+			s.clients[nick] = conn
+			s.Unlock()
+			if user && !welcomed {
+				welcomed = true
+				reply(":irctest 001 %s :Welcome to irctest", nick)
+			}
+		case "USER":
+			user = true
+			if nick != "" && !welcomed {
+				welcomed = true
+				reply(":irctest 001 %s :Welcome to irctest", nick)
+			}
+		case "JOIN":
+			if len(fields) > 1 {
+				reply(":%s!%s@irctest JOIN %s", nick, nick, fields[1])
+			}
+		case "PING":
+			reply(":irctest PONG irctest %s", strings.Join(fields[1:], " "))
+		case "QUIT":
+			s.Lock()
+			delete(s.clients, nick)
+			s.Unlock()
+			return
+		}
+	}
+
+	s.Lock()
+	if s.clients[nick] == conn {
+		delete(s.clients, nick)
+	}
+	s.Unlock()
+}
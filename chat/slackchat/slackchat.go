@@ -0,0 +1,139 @@
+// Package slackchat connects the bot to Slack through the Events API.
+package slackchat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/chat"
+
+	"github.com/nlopes/slack"
+	"github.com/nlopes/slack/slackevents"
+)
+
+// Client posts messages to Slack
+type Client struct {
+	api *slack.Client
+}
+
+// New returns a Client posting with the bot token, options are passed on to the Slack API client
+func New(botToken string, options ...slack.Option) *Client {
+	return &Client{api: slack.New(botToken, options...)}
+}
+
+// PostText posts a text to a channel
+func (c *Client) PostText(channelID string, text string) error {
+	_, _, err := c.api.PostMessage(channelID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// PostImage posts a text along with an image, uploaded images are shared with files.upload
+// and linked ones are shown as an attachment
+func (c *Client) PostImage(channelID string, text string, image chat.Image) error {
+	if image.Data != nil {
+		_, err := c.api.UploadFile(slack.FileUploadParameters{
+			Reader:         bytes.NewReader(image.Data),
+			Filetype:       strings.TrimPrefix(path.Ext(image.Name), "."),
+			Filename:       image.Name,
+			InitialComment: text,
+			Channels:       []string{channelID},
+		})
+		return err
+	}
+
+	attachment := slack.Attachment{
+		ImageURL: image.URL,
+		Color:    image.Color,
+	}
+	_, _, err := c.api.PostMessage(channelID, slack.MsgOptionText(text, false), slack.MsgOptionAttachments(attachment))
+	return err
+}
+
+// PostEphemeral posts a text to a channel that only the given user can see
+func (c *Client) PostEphemeral(channelID string, userID string, text string) error {
+	_, err := c.api.PostEphemeral(channelID, userID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// PostThreadReply posts a text as a reply in a thread of a channel
+func (c *Client) PostThreadReply(channelID string, threadID string, text string) error {
+	_, _, err := c.api.PostMessage(channelID, slack.MsgOptionText(text, false), slack.MsgOptionTS(threadID))
+	return err
+}
+
+// EventHandler receives the Slack Events API requests and hands the messages to the handler
+type EventHandler struct {
+	SigningKey string
+	Handler    chat.Handler
+}
+
+func (e EventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sv, err := slack.NewSecretsVerifier(r.Header, e.SigningKey)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, err := sv.Write(body); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := sv.Ensure(); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if eventsAPIEvent.Type == slackevents.URLVerification {
+		var r *slackevents.ChallengeResponse
+		err := json.Unmarshal([]byte(body), &r)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text")
+		w.Write([]byte(r.Challenge))
+	}
+	if eventsAPIEvent.Type == slackevents.CallbackEvent {
+		innerEvent := eventsAPIEvent.InnerEvent
+		switch ev := innerEvent.Data.(type) {
+		case *slackevents.AppMentionEvent:
+			e.Handler.HandleMessage(chat.Message{
+				ChannelID: ev.Channel,
+				ThreadID:  ev.ThreadTimeStamp,
+				Timestamp: ev.TimeStamp,
+				UserID:    ev.User,
+				Text:      ev.Text,
+				Mention:   true,
+			})
+		case *slackevents.MessageEvent:
+			e.Handler.HandleMessage(Message(ev))
+		}
+	}
+}
+
+// Message converts a Slack message event to a chat message
+func Message(ev *slackevents.MessageEvent) chat.Message {
+	return chat.Message{
+		ChannelID: ev.Channel,
+		ThreadID:  ev.ThreadTimeStamp,
+		Timestamp: ev.TimeStamp,
+		UserID:    ev.User,
+		Text:      ev.Text,
+		// direct message channel IDs start with a D
+		Direct: strings.HasPrefix(ev.Channel, "D") || ev.ChannelType == "im",
+	}
+}
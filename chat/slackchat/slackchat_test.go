@@ -0,0 +1,73 @@
+package slackchat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dyslexicat/collab-chess/chat/slackchat/slacktest"
+)
+
+const channelID = "C0001"
+
+// logCalls shows what the bot posted when a test fails
+func logCalls(t *testing.T, h *slacktest.Harness) {
+	t.Helper()
+	if !t.Failed() {
+		return
+	}
+	for _, call := range h.API.Calls() {
+		t.Logf("%s %q", call.Method, call.Text())
+	}
+}
+
+func TestFoolsMate(t *testing.T) {
+	h, err := slacktest.Play(channelID, slacktest.FoolsMate)
+	defer h.Close()
+	defer logCalls(t, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range h.API.Calls() {
+		if strings.Contains(call.Text(), "is not a valid move right now") {
+			if call.Method != "chat.postEphemeral" || call.Values.Get("user") != "U0002" {
+				t.Fatalf("got %s for %q, want the invalid move told to U0002 alone", call.Method, call.Values.Get("user"))
+			}
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	h := slacktest.New(channelID, slacktest.ScriptedEngine())
+	defer h.Close()
+	defer logCalls(t, h)
+
+	if err := h.CheckEndpoint(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMentionsAreAnsweredWithAnIntroduction(t *testing.T) {
+	h := slacktest.New(channelID, slacktest.ScriptedEngine())
+	defer h.Close()
+	defer logCalls(t, h)
+
+	h.Events.Mention(channelID, "U0001", "<@UBOT> hi")
+	call, err := h.API.Wait("!help to get help", h.Timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Method != "chat.postMessage" || call.Values.Get("channel") != channelID {
+		t.Fatalf("got %s in %q, want the introduction posted in %s", call.Method, call.Values.Get("channel"), channelID)
+	}
+
+	// messages that aren't commands are left alone
+	h.Events.Message(channelID, "U0001", "good game everyone")
+	h.Events.Message(channelID, "U0001", "!help")
+	if _, err := h.API.Wait("To vote on a move", h.Timeout); err != nil {
+		t.Fatal(err)
+	}
+	if calls := h.API.Calls(); len(calls) != 2 {
+		t.Fatalf("got %d calls, want the chat message to be ignored", len(calls))
+	}
+}
@@ -0,0 +1,127 @@
+// Package slacktest drives the bot through the Slack Events API without Slack: events are signed
+// with a test secret and posted to the handler, and the Web API calls the bot makes in return
+// are answered and recorded by a local server.
+package slacktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call is a request the bot made to the Slack Web API
+type Call struct {
+	// Method is the Web API method like chat.postMessage
+	Method string
+	// Values are the form values of the request
+	Values url.Values
+	// File is the content of an uploaded file
+	File []byte
+}
+
+// Text returns the text of a posted message or the comment of an uploaded file
+func (c Call) Text() string {
+	if c.Method == "files.upload" {
+		return c.Values.Get("initial_comment")
+	}
+	return c.Values.Get("text")
+}
+
+// API is a fake Slack Web API answering every method with success
+type API struct {
+	server *httptest.Server
+	calls  []Call
+	// waited is the number of calls Wait already went through
+	waited int
+	notify chan struct{}
+	sync.Mutex
+}
+
+// NewAPI starts a fake Web API, pass URL to the Slack client with slack.OptionAPIURL
+func NewAPI() *API {
+	a := &API{notify: make(chan struct{}, 1)}
+	a.server = httptest.NewServer(http.HandlerFunc(a.serve))
+	return a
+}
+
+// URL returns the base URL of the API
+func (a *API) URL() string {
+	return a.server.URL + "/api/"
+}
+
+// Close shuts the server down
+func (a *API) Close() {
+	a.server.Close()
+}
+
+func (a *API) serve(w http.ResponseWriter, r *http.Request) {
+	call := Call{Method: strings.TrimPrefix(r.URL.Path, "/api/")}
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	call.Values = r.Form
+	if file, _, err := r.FormFile("file"); err == nil {
+		call.File, _ = ioutil.ReadAll(file)
+		file.Close()
+	}
+
+	a.Lock()
+	a.calls = append(a.calls, call)
+	a.Unlock()
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+
+	response := map[string]interface{}{
+		"ok":         true,
+		"channel":    call.Values.Get("channel"),
+		"ts":         fmt.Sprintf("%d.000100", time.Now().Unix()),
+		"message_ts": fmt.Sprintf("%d.000200", time.Now().Unix()),
+	}
+	if call.Method == "files.upload" {
+		response["file"] = map[string]interface{}{"id": "F0001", "name": call.Values.Get("filename")}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Calls returns every call made so far
+func (a *API) Calls() []Call {
+	a.Lock()
+	defer a.Unlock()
+	calls := make([]Call, len(a.calls))
+	copy(calls, a.calls)
+	return calls
+}
+
+// Wait waits for a call whose text contains text. Only calls made after the one
+// the last Wait returned are looked at, so a script can wait for the replies in order
+func (a *API) Wait(text string, timeout time.Duration) (Call, error) {
+	deadline := time.After(timeout)
+	for {
+		a.Lock()
+		for i := a.waited; i < len(a.calls); i++ {
+			if strings.Contains(a.calls[i].Text(), text) {
+				a.waited = i + 1
+				call := a.calls[i]
+				a.Unlock()
+				return call, nil
+			}
+		}
+		a.Unlock()
+
+		select {
+		case <-a.notify:
+		case <-deadline:
+			return Call{}, fmt.Errorf("the bot did not post %q within %s", text, timeout)
+		}
+	}
+}
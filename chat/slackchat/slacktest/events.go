@@ -0,0 +1,100 @@
+package slacktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sign returns the X-Slack-Signature header of a request body sent at timestamp
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Events posts signed Events API payloads to a handler like slackchat.EventHandler
+type Events struct {
+	Secret  string
+	Handler http.Handler
+
+	// sent numbers the messages to give each one a timestamp of its own
+	sent int
+	sync.Mutex
+}
+
+// Post signs the payload and serves it to the handler
+func (e *Events) Post(payload interface{}) *httptest.ResponseRecorder {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", Sign(e.Secret, timestamp, body))
+
+	w := httptest.NewRecorder()
+	e.Handler.ServeHTTP(w, r)
+	return w
+}
+
+// URLVerification sends the challenge Slack sends when the events URL is set up
+func (e *Events) URLVerification(challenge string) *httptest.ResponseRecorder {
+	return e.Post(map[string]interface{}{
+		"token":     "test",
+		"type":      "url_verification",
+		"challenge": challenge,
+	})
+}
+
+// Message sends a message a user wrote in a channel
+func (e *Events) Message(channelID string, userID string, text string) *httptest.ResponseRecorder {
+	return e.Post(e.callback(map[string]interface{}{
+		"type":         "message",
+		"channel":      channelID,
+		"channel_type": "channel",
+		"user":         userID,
+		"text":         text,
+	}))
+}
+
+// Mention sends a message mentioning the bot
+func (e *Events) Mention(channelID string, userID string, text string) *httptest.ResponseRecorder {
+	return e.Post(e.callback(map[string]interface{}{
+		"type":    "app_mention",
+		"channel": channelID,
+		"user":    userID,
+		"text":    text,
+	}))
+}
+
+// callback wraps an event the way the Events API delivers it
+func (e *Events) callback(event map[string]interface{}) map[string]interface{} {
+	e.Lock()
+	e.sent++
+	sent := e.sent
+	e.Unlock()
+
+	event["ts"] = fmt.Sprintf("%d.%06d", time.Now().Unix(), sent)
+	event["event_ts"] = event["ts"]
+	return map[string]interface{}{
+		"token":      "test",
+		"team_id":    "T0001",
+		"api_app_id": "A0001",
+		"type":       "event_callback",
+		"event_id":   fmt.Sprintf("Ev%04d", sent),
+		"event_time": time.Now().Unix(),
+		"event":      event,
+	}
+}
@@ -0,0 +1,169 @@
+package slacktest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat/slackchat"
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/handler"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/nlopes/slack"
+	"github.com/notnil/chess"
+)
+
+// Secret is the signing secret the harness signs its events with
+const Secret = "slacktest-signing-secret"
+
+// Step is a message sent during a scripted game and the text the bot has to post after it
+type Step struct {
+	UserID string
+	// Text is sent to the game channel, nothing is sent when it is empty
+	Text string
+	// Want is a part of the text the bot posts in reply, nothing is waited for when it is empty
+	Want string
+}
+
+// Script is a whole game played through Slack events
+type Script struct {
+	Name string
+	// BotMoves are the moves the bot plays, in order
+	BotMoves []string
+	Steps    []Step
+}
+
+// FoolsMate is the shortest possible game, the channel plays Black and the bot walks into mate
+var FoolsMate = Script{
+	Name:     "Fool's mate",
+	BotMoves: []string{"f3", "g4"},
+	Steps: []Step{
+		{UserID: "U0001", Text: "!start black", Want: "Hackalackers are playing: Black"},
+		{Want: "I made my move"},
+		{UserID: "U0002", Text: "!move e4", Want: "is not a valid move right now"},
+		{UserID: "U0001", Text: "!move e5"},
+		{UserID: "U0002", Text: "!move d5"},
+		{UserID: "U0003", Text: "!move e5"},
+		{UserID: "U0002", Text: "!votes", Want: "*e5* (2 votes), *d5* (1 vote)"},
+		{Want: "Top voted move was: *e5*"},
+		{Want: "I made my move"},
+		{UserID: "U0003", Text: "!board text", Want: "Black to move"},
+		{UserID: "U0002", Text: "!move Qh4"},
+		{Want: "Top voted move was: *Qh4*"},
+		{Want: "0-1 by Checkmate"},
+		{Want: "Here is how the game went"},
+	},
+}
+
+// scriptedEngine plays moves given in advance
+type scriptedEngine struct {
+	moves []string
+	sync.Mutex
+}
+
+// ScriptedEngine returns a Bot.NewEngine playing the moves in order. Every game started by the bot
+// gets an engine of its own starting from the first move
+func ScriptedEngine(moves ...string) func() (handler.Engine, error) {
+	return func() (handler.Engine, error) {
+		return &scriptedEngine{moves: moves}, nil
+	}
+}
+
+func (e *scriptedEngine) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	e.Lock()
+	defer e.Unlock()
+	if len(e.moves) == 0 {
+		return nil, game.Evaluation{}, fmt.Errorf("the script has no moves left")
+	}
+
+	san := e.moves[0]
+	e.moves = e.moves[1:]
+	move, err := chess.AlgebraicNotation{}.Decode(position, san)
+	if err != nil {
+		return nil, game.Evaluation{}, fmt.Errorf("the scripted move %s does not fit the position: %v", san, err)
+	}
+	return move, game.Evaluation{}, nil
+}
+
+func (e *scriptedEngine) Close() error {
+	return nil
+}
+
+// Harness is a bot connected to a fake Slack
+type Harness struct {
+	API    *API
+	Events *Events
+	Bot    handler.Bot
+	// Timeout is how long a step waits for the bot to reply
+	Timeout time.Duration
+}
+
+// New returns a harness whose bot plays in channelID with the given engine. Turns end
+// about a second after the first vote, and boards are linked instead of uploaded
+func New(channelID string, newEngine func() (handler.Engine, error)) *Harness {
+	api := NewAPI()
+	bot := handler.Bot{
+		Chat:         slackchat.New("xoxb-slacktest", slack.OptionAPIURL(api.URL())),
+		GameStorage:  game.NewMemoryStore(),
+		LinkRenderer: rendering.NewRenderLink("slacktest.invalid", Secret),
+		GameChannel:  channelID,
+		Settings:     handler.NewChannelSettings(),
+		NewEngine:    newEngine,
+		VoteDuration: 500 * time.Millisecond,
+	}
+	return &Harness{
+		API:     api,
+		Events:  &Events{Secret: Secret, Handler: slackchat.EventHandler{SigningKey: Secret, Handler: bot}},
+		Bot:     bot,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Close shuts the fake Web API down
+func (h *Harness) Close() {
+	h.API.Close()
+}
+
+// Run plays a script in the bot's channel and returns the first step that went wrong
+func (h *Harness) Run(script Script) error {
+	for i, step := range script.Steps {
+		if step.Text != "" {
+			if w := h.Events.Message(h.Bot.GameChannel, step.UserID, step.Text); w.Code != http.StatusOK {
+				return fmt.Errorf("%s, step %d: the events handler answered %d", script.Name, i+1, w.Code)
+			}
+		}
+		if step.Want == "" {
+			continue
+		}
+		if _, err := h.API.Wait(step.Want, h.Timeout); err != nil {
+			return fmt.Errorf("%s, step %d: %v", script.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+// Play runs a script against a new bot with the script's engine
+func Play(channelID string, script Script) (*Harness, error) {
+	h := New(channelID, ScriptedEngine(script.BotMoves...))
+	return h, h.Run(script)
+}
+
+// CheckEndpoint makes sure the events endpoint answers Slack's URL verification
+// and turns away requests that are not signed with the secret
+func (h *Harness) CheckEndpoint() error {
+	if w := h.Events.URLVerification("slacktest-challenge"); w.Code != http.StatusOK || w.Body.String() != "slacktest-challenge" {
+		return fmt.Errorf("URL verification answered %d %q", w.Code, w.Body.String())
+	}
+
+	forged := &Events{Secret: "not-the-secret", Handler: h.Events.Handler}
+	before := len(h.API.Calls())
+	if w := forged.Message(h.Bot.GameChannel, "U0001", "!help"); w.Code != http.StatusUnauthorized {
+		return fmt.Errorf("a wrongly signed event was answered %d", w.Code)
+	}
+	if len(h.API.Calls()) != before {
+		return fmt.Errorf("the bot replied to a wrongly signed event")
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// chess-cli plays the channel's game in a terminal: the same game loop, engine and commands
+// as the chat bot, with simulated voters joining every vote. It needs stockfish in the PATH.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat"
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/handler"
+	"github.com/dyslexicat/collab-chess/rendering"
+	"github.com/notnil/chess"
+)
+
+// channelID is the only channel of the terminal
+const channelID = "terminal"
+
+const helpText = `Commands:
+  start [white|black]   start a game, the color is random when left out
+  move <san>            vote on a move as the current voter, the simulated voters vote too
+  vote <name> <san>     vote on a move as someone else
+  as <name>             change the current voter
+  play                  end the turn and play the top voted move right away
+  board [flip]          show the board
+  votes                 show the votes of this turn
+  pgn                   show the moves so far
+  help                  show this help
+  quit                  leave`
+
+// cli holds the state of the terminal session
+type cli struct {
+	bot    handler.Bot
+	out    *terminal
+	voter  string
+	voters []string
+	agree  float64
+}
+
+func main() {
+	voters := flag.Int("voters", 2, "number of simulated voters joining every vote")
+	agree := flag.Float64("agree", 0.5, "chance a simulated voter votes for the same move as you, they pick a random legal move otherwise")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the simulated voters and of the bot's thinking time")
+	name := flag.String("name", "you", "name of the current voter")
+	analysisMoveTime := flag.Duration("analysis", 300*time.Millisecond, "time the engine looks at every position after the game, 0 turns the analysis off")
+	color := flag.Bool("color", true, "use ANSI escape codes for bold text")
+	verbose := flag.Bool("verbose", false, "print the log of the game loop and the engine to stderr")
+	flag.Parse()
+
+	rand.Seed(*seed)
+	// the game logs every vote, that is what the terminal shows anyway
+	log.SetOutput(ioutil.Discard)
+	if *verbose {
+		log.SetOutput(os.Stderr)
+	}
+	log.SetFlags(0)
+	log.SetPrefix("log: ")
+
+	out := &terminal{out: os.Stdout, color: *color}
+	c := &cli{
+		bot: handler.Bot{
+			Chat:             out,
+			GameStorage:      game.NewMemoryStore(),
+			LinkRenderer:     rendering.NewRenderLink("", ""),
+			GameChannel:      channelID,
+			Settings:         handler.NewChannelSettings(),
+			AnalysisMoveTime: *analysisMoveTime,
+		},
+		out:   out,
+		voter: *name,
+		agree: *agree,
+	}
+	for i := 1; i <= *voters; i++ {
+		c.voters = append(c.voters, fmt.Sprintf("voter%d", i))
+	}
+
+	fmt.Println(helpText)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			return
+		}
+		if !c.run(strings.Fields(scanner.Text())) {
+			return
+		}
+	}
+}
+
+// run handles a command and tells whether the session goes on
+func (c *cli) run(fields []string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+
+	args := strings.Join(fields[1:], " ")
+	switch fields[0] {
+	case "start":
+		c.send(c.voter, strings.TrimSpace("!start "+args))
+	case "move":
+		if args == "" {
+			fmt.Println("usage: move <san>, like move e4 or move Nf3")
+			return true
+		}
+		c.send(c.voter, "!move "+args)
+		c.simulateVotes(args)
+	case "vote":
+		if len(fields) != 3 {
+			fmt.Println("usage: vote <name> <san>")
+			return true
+		}
+		c.send(fields[1], "!move "+fields[2])
+	case "as":
+		if args == "" {
+			fmt.Println("usage: as <name>")
+			return true
+		}
+		c.voter = args
+	case "play":
+		c.playTopVote()
+	case "board":
+		c.send(c.voter, strings.TrimSpace("!board "+args))
+	case "votes":
+		c.send(c.voter, "!votes")
+	case "pgn":
+		c.pgn()
+	case "help":
+		fmt.Println(helpText)
+	case "quit", "exit":
+		return false
+	default:
+		fmt.Printf("unknown command %q, type help to see the commands\n", fields[0])
+	}
+	return true
+}
+
+// send hands a message to the bot as if the voter typed it in the channel
+func (c *cli) send(voter string, text string) {
+	c.bot.HandleMessage(chat.Message{
+		ChannelID: channelID,
+		UserID:    voter,
+		Text:      text,
+		Timestamp: fmt.Sprint(time.Now().UnixNano()),
+	})
+}
+
+// simulateVotes lets every simulated voter vote, either for the same move or a random legal one
+func (c *cli) simulateVotes(san string) {
+	gm, err := c.bot.GameStorage.RetrieveGame()
+	if err != nil {
+		return
+	}
+	snapshot := gm.Snapshot()
+	if snapshot.TurnPlayer().ID == "chessbot" {
+		return
+	}
+
+	position := snapshot.Position
+	moves := position.ValidMoves()
+	for _, voter := range c.voters {
+		move := san
+		if rand.Float64() >= c.agree && len(moves) > 0 {
+			move = chess.AlgebraicNotation{}.Encode(position, moves[rand.Intn(len(moves))])
+		}
+		c.send(voter, "!move "+move)
+	}
+}
+
+// playTopVote ends the turn without waiting for the vote timer, the game loop plays the bot's answer
+func (c *cli) playTopVote() {
+	gm, err := c.bot.GameStorage.RetrieveGame()
+	if err != nil {
+		fmt.Println("There isn't an active game at the moment, type start to start one")
+		return
+	}
+
+	move, err := gm.MoveTopVote()
+	if err != nil {
+		fmt.Println("Nobody has voted yet, vote with move <san>")
+		return
+	}
+	c.out.PostText(channelID, fmt.Sprintf("Top voted move was: *%s*", move))
+}
+
+// pgn prints the moves of the game so far
+func (c *cli) pgn() {
+	gm, err := c.bot.GameStorage.RetrieveGame()
+	if err != nil {
+		fmt.Println("There isn't an active game at the moment, type start to start one")
+		return
+	}
+	c.out.PostText(channelID, gm.PGN())
+}
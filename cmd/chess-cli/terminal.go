@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dyslexicat/collab-chess/chat"
+)
+
+var (
+	// boldRegex finds Slack's *bold* which is drawn with the ANSI bold escape code
+	boldRegex = regexp.MustCompile(`\*([^*\n]+)\*`)
+	// mentionRegex finds Slack's <@U123> mentions, voters in the terminal are just names
+	mentionRegex = regexp.MustCompile(`<@([^>|]+)(?:\|[^>]*)?>`)
+)
+
+// terminal is a chat client that prints everything the bot posts
+type terminal struct {
+	out io.Writer
+	// color turns the ANSI escape codes on
+	color bool
+	sync.Mutex
+}
+
+func (t *terminal) print(prefix string, text string) error {
+	t.Lock()
+	defer t.Unlock()
+	_, err := fmt.Fprintf(t.out, "%s%s\n", prefix, t.format(text))
+	return err
+}
+
+// format translates Slack's mrkdwn to plain terminal text
+func (t *terminal) format(text string) string {
+	text = strings.ReplaceAll(text, "```\n", "")
+	text = strings.ReplaceAll(text, "\n```", "")
+	bold := "$1"
+	if t.color {
+		bold = "\x1b[1m$1\x1b[0m"
+	}
+	text = boldRegex.ReplaceAllString(text, bold)
+	text = mentionRegex.ReplaceAllString(text, "$1")
+	return chat.ReplaceEmoji(text)
+}
+
+// PostText prints a text
+func (t *terminal) PostText(channelID string, text string) error {
+	return t.print("", text)
+}
+
+// PostImage prints the text followed by the alternative text of the image, like a text board
+func (t *terminal) PostImage(channelID string, text string, image chat.Image) error {
+	if image.Alt != "" {
+		text += "\n" + image.Alt
+	}
+	return t.print("", text)
+}
+
+// PostEphemeral prints a text meant for a single voter
+func (t *terminal) PostEphemeral(channelID string, userID string, text string) error {
+	return t.print(fmt.Sprintf("(to %s) ", userID), text)
+}
+
+// PostThreadReply prints a text, the terminal has no threads
+func (t *terminal) PostThreadReply(channelID string, threadID string, text string) error {
+	return t.print("", text)
+}
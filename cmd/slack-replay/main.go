@@ -0,0 +1,47 @@
+// slack-replay plays scripted games against the bot through signed Slack events and a fake
+// Slack Web API, and exits with an error when the bot does not answer as the script expects.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/dyslexicat/collab-chess/chat/slackchat/slacktest"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "print the log of the bot and every Slack API call")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	failed := false
+	for _, script := range []slacktest.Script{slacktest.FoolsMate} {
+		h, err := slacktest.Play("C0001", script)
+		if err == nil {
+			err = h.CheckEndpoint()
+		}
+		if *verbose {
+			for _, call := range h.API.Calls() {
+				fmt.Printf("  %s %q\n", call.Method, call.Text())
+			}
+		}
+		h.Close()
+
+		if err != nil {
+			failed = true
+			fmt.Println("FAIL", err)
+			continue
+		}
+		fmt.Println("ok  ", script.Name)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
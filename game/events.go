@@ -0,0 +1,108 @@
+package game
+
+import (
+	"sort"
+	"sync"
+)
+
+// EventType is the kind of change that happened to a game
+type EventType string
+
+// Changes that are announced to the subscribers of a game
+const (
+	// EventVote is sent when a player votes on a move
+	EventVote EventType = "vote"
+	// EventMove is sent when a move is played, either the top voted one or the bot's
+	EventMove EventType = "move"
+)
+
+// eventBuffer is how many events a subscriber can fall behind before events are dropped
+const eventBuffer = 16
+
+// Event announces a change of the state of a game
+type Event struct {
+	Type EventType `json:"type"`
+	// PlayerID is who voted, chessbot for the bot's moves and empty for the top voted move
+	PlayerID string `json:"player_id"`
+	// Move is the voted or played move in algebraic notation
+	Move string `json:"move"`
+}
+
+// subscribers hands out event channels and sends them the events of a game
+type subscribers struct {
+	channels map[chan Event]struct{}
+	sync.Mutex
+}
+
+// Subscribe returns a channel receiving the events of the game and a function to stop receiving them.
+// Events are dropped when the subscriber doesn't keep up, they only hint that the state should be read again
+func (g *Game) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+
+	g.subscribers.Lock()
+	if g.subscribers.channels == nil {
+		g.subscribers.channels = make(map[chan Event]struct{})
+	}
+	g.subscribers.channels[ch] = struct{}{}
+	g.subscribers.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			g.subscribers.Lock()
+			delete(g.subscribers.channels, ch)
+			g.subscribers.Unlock()
+		})
+	}
+}
+
+// Subscribers returns how many subscribers are receiving the events of the game
+func (g *Game) Subscribers() int {
+	g.subscribers.Lock()
+	defer g.subscribers.Unlock()
+	return len(g.subscribers.channels)
+}
+
+// notify sends the event to every subscriber without waiting for slow ones
+func (g *Game) notify(event Event) {
+	g.subscribers.Lock()
+	defer g.subscribers.Unlock()
+	for ch := range g.subscribers.channels {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// VoteCount is a voted move and everybody who voted for it
+type VoteCount struct {
+	Move    string   `json:"move"`
+	Count   int      `json:"count"`
+	Players []string `json:"players"`
+}
+
+// TallyVotes groups the votes (player ID -> move) by move, most voted first
+func TallyVotes(votes map[string]string) []VoteCount {
+	byMove := make(map[string]*VoteCount)
+	for playerID, move := range votes {
+		if _, ok := byMove[move]; !ok {
+			byMove[move] = &VoteCount{Move: move}
+		}
+		byMove[move].Count++
+		byMove[move].Players = append(byMove[move].Players, playerID)
+	}
+
+	tally := make([]VoteCount, 0, len(byMove))
+	for _, vc := range byMove {
+		sort.Strings(vc.Players)
+		tally = append(tally, *vc)
+	}
+	sort.Slice(tally, func(i, j int) bool {
+		if tally[i].Count != tally[j].Count {
+			return tally[i].Count > tally[j].Count
+		}
+		return tally[i].Move < tally[j].Move
+	})
+	return tally
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileStore keeps the active game in memory like MemoryStore
+// but also appends the records of finished games to a JSON lines file so statistics survive restarts
+type FileStore struct {
+	*MemoryStore
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a FileStore pointer with the records already saved at path loaded
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// vote histories of long games make for long lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		store.MemoryStore.SaveRecord(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// SaveRecord appends the summary of a finished game to the file and keeps it in memory
+func (f *FileStore) SaveRecord(record Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return f.MemoryStore.SaveRecord(record)
+}
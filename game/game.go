@@ -23,11 +23,26 @@ const (
 	Black Color = "Black"
 )
 
+// Other returns the opposite color
+func (c Color) Other() Color {
+	if c == White {
+		return Black
+	}
+	return White
+}
+
 var ColorMap = map[Color]chess.Color{
 	White: chess.White,
 	Black: chess.Black,
 }
 
+const (
+	// VoteDuration is how long a turn of the human players lasts after the first vote
+	VoteDuration = 40 * time.Second
+	// InactivityTimeout is how long the human players can go without moving before the game is stopped
+	InactivityTimeout = 8 * time.Minute
+)
+
 // TimeProvider is a closure that returns the current time as determined by the provider
 type TimeProvider func() time.Time
 
@@ -35,19 +50,53 @@ var defaultTimeProvider TimeProvider = func() time.Time {
 	return time.Now()
 }
 
-// Game is a chess game
+// Game is a chess game. It is safe for concurrent use, every method locks the game itself
 type Game struct {
-	ID           string
-	game         *chess.Game
-	started      bool
+	ID   string
+	game *chess.Game
+	// Players never changes once the game is created
 	Players      map[Color]Player
+	started      bool
 	votes        map[string]string
+	voteHistory  []VoteRound
 	playersVoted uniqueVoters
+	createdAt    time.Time
 	lastMoved    time.Time
 	firstVoted   time.Time
+	voteDuration time.Duration
 	checkedTile  *chess.Square
+	eval         *Evaluation
 	timeProvider TimeProvider
-	sync.Mutex
+	subscribers  subscribers
+	mu           sync.Mutex
+}
+
+// Evaluation is an engine score of a position from White's point of view
+type Evaluation struct {
+	// CP is the advantage in centipawns
+	CP int
+	// Mate is the number of moves until mate, negative when Black is mating and zero when no mate was found
+	Mate int
+	// Line is the continuation the engine expects from the evaluated position, it can be empty
+	Line []*chess.Move
+}
+
+// String formats the evaluation like +1.25, -0.40 or #-3
+func (e Evaluation) String() string {
+	if e.Mate != 0 {
+		return fmt.Sprintf("#%d", e.Mate)
+	}
+	return fmt.Sprintf("%+.2f", float64(e.CP)/100)
+}
+
+// VoteRound records the votes of a single turn of the human players
+type VoteRound struct {
+	// Ply is the index of the played move in the game, starting at 0 for White's first move
+	Ply int `json:"ply"`
+	// Votes maps player IDs to the move they voted for in algebraic notation
+	Votes map[string]string `json:"votes"`
+	// Played is the move that won the vote
+	Played string `json:"played"`
 }
 
 // Player represents a human Chess player
@@ -61,8 +110,10 @@ func NewGame(ID string, pieceColor string, players ...Player) *Game {
 	gm := &Game{
 		ID:           ID,
 		game:         chess.NewGame(),
+		createdAt:    time.Now(),
 		lastMoved:    time.Now(),
 		firstVoted:   time.Now(),
+		voteDuration: VoteDuration,
 		votes:        make(map[string]string),
 		playersVoted: uniqueVoters{},
 		timeProvider: defaultTimeProvider,
@@ -108,11 +159,20 @@ func attachPlayers(g *Game, pieceColor string, players ...Player) {
 
 // TurnPlayer returns which player should move next
 func (g *Game) TurnPlayer() Player {
-	return g.Players[g.Turn()]
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.Players[g.turn()]
 }
 
 // Turn returns which color should move next
 func (g *Game) Turn() Color {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.turn()
+}
+
+// turn returns which color should move next, the caller holds the lock
+func (g *Game) turn() Color {
 	switch g.game.Position().Turn() {
 	case chess.White:
 		return White
@@ -125,6 +185,8 @@ func (g *Game) Turn() Color {
 
 // FEN serializer
 func (g *Game) FEN() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.game.FEN()
 }
 
@@ -140,54 +202,62 @@ func (g *Game) GetColor(ID string) (Color, error) {
 
 // Move a Chess piece based on standard algebraic notation (d2d4, etc)
 func (g *Game) Move(san string) (*chess.Move, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.move(san)
+}
+
+// move plays a move in algebraic notation, the caller holds the lock
+func (g *Game) move(san string) (*chess.Move, error) {
 	err := g.game.MoveStr(san)
 	if err != nil {
 		return nil, err
 	}
 	g.started = true
 	g.lastMoved = g.timeProvider()
-	return g.LastMove(), nil
+	return g.lastMove(), nil
 }
 
-// BotMove simulates a move for our bot player
+// BotMove simulates a move for our bot player, it fails when it is not the bot's turn
 func (g *Game) BotMove(m *chess.Move) error {
-	g.Lock()
-	defer g.Unlock()
-	err := g.game.Move(m)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Players[g.turn()].ID != "chessbot" {
+		return fmt.Errorf("it is not the bot's turn")
+	}
+
+	position := g.game.Position()
+	if err := g.game.Move(m); err != nil {
+		return err
+	}
 	g.started = true
 	g.lastMoved = g.timeProvider()
-	return err
+	g.notify(Event{Type: EventMove, PlayerID: "chessbot", Move: chess.AlgebraicNotation{}.Encode(position, g.lastMove())})
+	return nil
 }
 
 // Outcome determines the outcome of the game (or no outcome)
 func (g *Game) Outcome() chess.Outcome {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.game.Outcome()
 }
 
 // ResultText will show the outcome of the game in textual format
 func (g *Game) ResultText() string {
-	outcome := g.Outcome()
-	if outcome == chess.Draw {
-		return fmt.Sprintf("Game completed. %s by %s.", g.Outcome(), g.game.Method())
-	}
-	var winningPlayer Player
-	if outcome == chess.WhiteWon {
-		winningPlayer = g.Players[White]
-	} else {
-		winningPlayer = g.Players[Black]
-	}
-
-	if winningPlayer.ID != "chessbot" {
-		uniquePlayers := g.playersVoted
-		return fmt.Sprintf("%s %s by %s", uniquePlayers, g.Outcome(), g.game.Method())
-	}
-
-	return fmt.Sprintf("I won this time :chess_pawn: Better luck next time! %s by %s", g.Outcome(), g.game.Method())
-
+	return g.Snapshot().ResultText()
 }
 
 // LastMove returns the last move done of the game
 func (g *Game) LastMove() *chess.Move {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastMove()
+}
+
+// lastMove returns the last move or nil, the caller holds the lock
+func (g *Game) lastMove() *chess.Move {
 	moves := g.game.Moves()
 	if len(moves) == 0 {
 		return nil
@@ -195,50 +265,182 @@ func (g *Game) LastMove() *chess.Move {
 	return moves[len(moves)-1]
 }
 
+// LastMoveSAN returns the last move in standard algebraic notation or an empty string if no move was played
+func (g *Game) LastMoveSAN() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	moves := g.game.Moves()
+	if len(moves) == 0 {
+		return ""
+	}
+	positions := g.game.Positions()
+	return chess.AlgebraicNotation{}.Encode(positions[len(positions)-2], moves[len(moves)-1])
+}
+
+// Moves returns every move played so far
+func (g *Game) Moves() []*chess.Move {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.game.Moves()
+}
+
+// HumanColor returns the piece color the human players are playing with
+func (g *Game) HumanColor() Color {
+	if g.Players[White].ID == "chessbot" {
+		return Black
+	}
+	return White
+}
+
+// CreatedAt returns the time the game was created
+func (g *Game) CreatedAt() time.Time {
+	return g.createdAt
+}
+
+// Method returns how the game ended
+func (g *Game) Method() chess.Method {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.game.Method()
+}
+
 // LastMoveTime returns the time when last piece was moved
 func (g *Game) LastMoveTime() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.lastMoved
 }
 
+// VoteDeadline returns when the top voted move gets played, it is false while nobody has voted this turn
+func (g *Game) VoteDeadline() (time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.votes) == 0 {
+		return time.Time{}, false
+	}
+	return g.firstVoted.Add(g.voteDuration), true
+}
+
+// VoteDuration returns how long a turn of the human players lasts after the first vote
+func (g *Game) VoteDuration() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.voteDuration
+}
+
+// SetVoteDuration changes how long a turn lasts after the first vote, VoteDuration by default.
+// It is meant for new games before they are stored, not for games that are being played
+func (g *Game) SetVoteDuration(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.voteDuration = d
+}
+
+// InactivityDeadline returns when the game is stopped if nobody moves until then
+func (g *Game) InactivityDeadline() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastMoved.Add(InactivityTimeout)
+}
+
 // FirstVoteTime returns the time of the first vote
 func (g *Game) FirstVoteTime() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.firstVoted
 }
 
 // Start indicates the game has been started
 func (g *Game) Start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.started = true
 }
 
 // Started determines if the game has been started
 func (g *Game) Started() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.started
 }
 
 // ValidMoves returns a list of all moves available to the current player's turn
 func (g *Game) ValidMoves() []*chess.Move {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.game.ValidMoves()
 }
 
 // Board representation as a string
 func (g *Game) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.game.Position().Board().Draw()
 }
 
-// Position returns the current position of the board for the engine move
+// PGN returns the moves of the game so far in PGN
+func (g *Game) PGN() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return strings.TrimSpace(g.game.String())
+}
+
+// Position returns the current position of the board for the engine move.
+// Positions are never changed by moves, later moves make new ones
 func (g *Game) Position() *chess.Position {
-	return g.game.Position()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	position := g.game.Position()
+	// positions work out their valid moves lazily, doing it under the lock lets callers share them
+	position.ValidMoves()
+	return position
+}
+
+// SetEvaluation stores the latest engine evaluation of the game
+func (g *Game) SetEvaluation(e Evaluation) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.eval = &e
+}
+
+// Evaluation returns the latest engine evaluation of the game if there is one
+func (g *Game) Evaluation() (Evaluation, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.eval == nil {
+		return Evaluation{}, false
+	}
+	return *g.eval, true
 }
 
-// Votes returns the voted moves so far
+// Votes returns a copy of the voted moves so far
 func (g *Game) Votes() map[string]string {
-	return g.votes
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	votes := make(map[string]string, len(g.votes))
+	for playerID, move := range g.votes {
+		votes[playerID] = move
+	}
+	return votes
 }
 
-// Vote votes on a move if it is a valid move
+// VoteHistory returns the votes of every turn the human players have played so far.
+// The rounds are never changed once a turn is over
+func (g *Game) VoteHistory() []VoteRound {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]VoteRound(nil), g.voteHistory...)
+}
+
+// Vote votes on a move if it is a valid move and the human players are to move
 func (g *Game) Vote(playerID string, move string) error {
-	g.Lock()
-	defer g.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// the turn may have passed to the bot since the voter last looked
+	if g.Players[g.turn()].ID == "chessbot" {
+		return fmt.Errorf("it is not the players' turn")
+	}
+
 	// this returns an error if it is not a valid move
 	_, err := chess.AlgebraicNotation{}.Decode(g.game.Position(), move)
 
@@ -250,6 +452,7 @@ func (g *Game) Vote(playerID string, move string) error {
 	if !ok {
 		log.Println(playerID, "is making a move:", move)
 		g.votes[playerID] = move
+		defer g.notify(Event{Type: EventVote, PlayerID: playerID, Move: move})
 	}
 
 	// if this was the first vote then we update the firstVoted
@@ -270,8 +473,8 @@ func (g *Game) Vote(playerID string, move string) error {
 
 // MoveTopVote moves the top voted piece
 func (g *Game) MoveTopVote() (string, error) {
-	g.Lock()
-	defer g.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
 	freqs := make(map[string]int)
 	for _, move := range g.votes {
@@ -292,21 +495,37 @@ func (g *Game) MoveTopVote() (string, error) {
 		return "", fmt.Errorf("there was no top vote")
 	}
 
-	_, err := g.Move(topVote)
+	ply := len(g.game.Moves())
+	_, err := g.move(topVote)
 
 	if err != nil {
 		return "", fmt.Errorf("there was a problem playing the move %s", topVote)
 	}
 
-	// reset votes after the voting
+	// keep who voted for what before resetting the votes for the next turn
+	g.voteHistory = append(g.voteHistory, VoteRound{Ply: ply, Votes: g.votes, Played: topVote})
 	g.votes = map[string]string{}
+	// the evaluation was of the position before this move, boards shouldn't show it anymore
+	g.eval = nil
+	g.notify(Event{Type: EventMove, Move: topVote})
 	return topVote, nil
 }
 
 // CheckedKing returns the square of a checked king if there is indeed a king in check.
 func (g *Game) CheckedKing() chess.Square {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.checkedKing()
+}
+
+// checkedKing returns the square of the king of the side to move, the caller holds the lock
+func (g *Game) checkedKing() chess.Square {
+	lastMove := g.lastMove()
+	if lastMove == nil {
+		return chess.NoSquare
+	}
 	squareMap := g.game.Position().Board().SquareMap()
-	lastMovePiece := squareMap[g.LastMove().S2()]
+	lastMovePiece := squareMap[lastMove.S2()]
 	for square, piece := range squareMap {
 		if piece.Type() == chess.King && piece.Color() == lastMovePiece.Color().Other() {
 			return square
@@ -0,0 +1,185 @@
+package game_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+// newGame returns a game where the human players play White against the bot
+func newGame() *game.Game {
+	return game.NewGame("test", "white", game.Player{ID: "chessbot"}, game.Player{ID: "U1"})
+}
+
+// decode reads a move in algebraic notation for the position
+func decode(t *testing.T, position *chess.Position, san string) *chess.Move {
+	t.Helper()
+	move, err := chess.AlgebraicNotation{}.Decode(position, san)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return move
+}
+
+func TestStoreGameKeepsTheActiveGame(t *testing.T) {
+	stores := map[string]game.ChessStorage{
+		"memory":   game.NewMemoryStore(),
+		"platform": game.NewPlatformStore(game.NewMemoryStore()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			first, second := newGame(), newGame()
+			if err := store.StoreGame(first); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.StoreGame(second); err == nil {
+				t.Fatal("want an error storing a game while another one is active")
+			}
+			if gm, _ := store.RetrieveGame(); gm != first {
+				t.Fatal("want the first game to stay the active one")
+			}
+
+			if err := store.RemoveGame(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.StoreGame(second); err != nil {
+				t.Fatalf("want a game to be stored once the last one is removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBotMoveOnlyOnTheBotsTurn(t *testing.T) {
+	gm := newGame()
+
+	if err := gm.BotMove(decode(t, gm.Position(), "e4")); err == nil {
+		t.Fatal("want an error when the bot moves on the turn of the human players")
+	}
+	if moves := gm.Moves(); len(moves) != 0 {
+		t.Fatalf("got moves %v, want the position unchanged", moves)
+	}
+
+	// a move the engine found for an earlier position
+	stale := decode(t, gm.Position(), "d4")
+	if _, err := gm.Move("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gm.BotMove(stale); err == nil {
+		t.Fatal("want an error for a move that doesn't fit the position")
+	}
+
+	if err := gm.BotMove(decode(t, gm.Position(), "e5")); err != nil {
+		t.Fatal(err)
+	}
+	if san := gm.LastMoveSAN(); san != "e5" {
+		t.Fatalf("got %q as the last move, want e5", san)
+	}
+	if err := gm.BotMove(decode(t, gm.Position(), "Nf3")); err == nil {
+		t.Fatal("want an error when the bot moves twice in a row")
+	}
+}
+
+// TestConcurrentVotesBoardsAndMoves has voters, board requests and the bot use a game at the same time,
+// run it with -race to check the locking of the game
+func TestConcurrentVotesBoardsAndMoves(t *testing.T) {
+	store := game.NewMemoryStore()
+	gm := newGame()
+	if err := store.StoreGame(gm); err != nil {
+		t.Fatal(err)
+	}
+	link := rendering.NewRenderLink("https://chess.example", "test-key")
+
+	events, unsubscribe := gm.Subscribe()
+	defer unsubscribe()
+	go func() {
+		for range events {
+		}
+	}()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				g, err := store.RetrieveGame()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				position := g.Position()
+				if moves := position.ValidMoves(); len(moves) > 0 {
+					// votes made on the bot's turn or on a position that is gone by now are turned away
+					g.Vote(fmt.Sprint("U", random.Intn(10)), chess.AlgebraicNotation{}.Encode(position, moves[random.Intn(len(moves))]))
+				}
+
+				snapshot := g.Snapshot()
+				if snapshot.Turn != snapshot.HumanColor && len(snapshot.Votes) != 0 {
+					t.Errorf("got votes %v on the bot's turn", snapshot.Votes)
+				}
+				if humanMoves := (len(snapshot.Moves) + 1) / 2; len(snapshot.VoteHistory) != humanMoves {
+					t.Errorf("got %d vote rounds for %d moves", len(snapshot.VoteHistory), len(snapshot.Moves))
+				}
+				if snapshot.FEN != snapshot.Position.String() {
+					t.Errorf("the FEN %s doesn't match the position %s", snapshot.FEN, snapshot.Position)
+				}
+				if board := rendering.TextBoard(snapshot, rendering.WithVotes(snapshot.Votes)); !strings.Contains(board, "to move") && snapshot.Outcome == chess.NoOutcome {
+					t.Errorf("got the text board %q", board)
+				}
+				if _, err := link.CreateLink(snapshot, rendering.WithVotes(snapshot.Votes)); err != nil {
+					t.Error(err)
+				}
+				g.VoteDeadline()
+				game.NewRecord(g)
+			}
+		}(int64(i))
+	}
+
+	random := rand.New(rand.NewSource(42))
+	var played int
+	for played < 60 && gm.Outcome() == chess.NoOutcome {
+		if gm.Turn() == gm.HumanColor() {
+			if _, err := gm.MoveTopVote(); err != nil {
+				// nobody voted yet
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			if votes := gm.Votes(); len(votes) != 0 {
+				t.Errorf("got votes %v right after the move, want them reset", votes)
+			}
+		} else {
+			moves := gm.ValidMoves()
+			if err := gm.BotMove(moves[random.Intn(len(moves))]); err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		played++
+		time.Sleep(2 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	if played == 0 {
+		t.Fatal("no move was played")
+	}
+	if moves := gm.Moves(); len(moves) != played {
+		t.Fatalf("got %d moves in the game, want the %d played", len(moves), played)
+	}
+}
@@ -1,10 +1,16 @@
 package game
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
-// MemoryStore implements the GameStore interface and holds the state in memory
+// MemoryStore implements the GameStore interface and holds the state in memory.
+// It is safe for concurrent use
 type MemoryStore struct {
-	game *Game
+	game    *Game
+	records []Record
+	mu      sync.Mutex
 }
 
 // NewMemoryStore returns a MemoryStore pointer
@@ -15,26 +21,62 @@ func NewMemoryStore() *MemoryStore {
 
 // RetrieveGame returns the game from the store
 func (m *MemoryStore) RetrieveGame() (*Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.game == nil {
 		return nil, fmt.Errorf("There is no game at the moment")
 	}
 
-	m.game.Lock()
-	defer m.game.Unlock()
-
 	return m.game, nil
 }
 
-// StoreGame stores the game in the store
+// StoreGame stores the game in the store unless there already is a game
 func (m *MemoryStore) StoreGame(game *Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.game != nil {
+		return fmt.Errorf("There is already a game in place")
+	}
+
 	m.game = game
 	return nil
 }
 
-// RemoveGame deletes the active game
+// RemoveGame deletes the active game, it does nothing when there is none
 func (m *MemoryStore) RemoveGame() error {
-	m.game.Lock()
-	defer m.game.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.game = nil
 	return nil
 }
+
+// SaveRecord keeps the summary of a finished game in memory
+func (m *MemoryStore) SaveRecord(record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, record)
+	return nil
+}
+
+// Records returns the summaries of every finished game
+func (m *MemoryStore) Records() ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := make([]Record, len(m.records))
+	copy(records, m.records)
+	return records, nil
+}
+
+// Record returns the summary of the finished game with the given ID
+func (m *MemoryStore) Record(id string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, record := range m.records {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	return Record{}, fmt.Errorf("There is no game with ID %s", id)
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// sanPattern matches algebraic notation with as much of the starting square as the writer gave,
+// like Nf3, Ngf3 or Ng1f3
+var sanPattern = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?x?([a-h][1-8])=?([NBRQ])?$`)
+
+// DecodeMove reads a move the way players write it: in algebraic notation, with more of the
+// starting square than needed like Ngf3, or in UCI notation like e2e4
+func DecodeMove(position *chess.Position, move string) (*chess.Move, error) {
+	move = strings.TrimRight(strings.TrimSpace(move), "+#!?")
+	if move == "" {
+		return nil, fmt.Errorf("empty move")
+	}
+	if decoded, err := (chess.AlgebraicNotation{}).Decode(position, move); err == nil {
+		return decoded, nil
+	}
+	if decoded, err := (chess.UCINotation{}).Decode(position, move); err == nil {
+		// the valid moves carry the tags that decoding on its own leaves out
+		for _, valid := range position.ValidMoves() {
+			if SameMove(valid, decoded) {
+				return valid, nil
+			}
+		}
+	}
+
+	parts := sanPattern.FindStringSubmatch(move)
+	if parts == nil {
+		return nil, fmt.Errorf("could not read move %q", move)
+	}
+	var found *chess.Move
+	for _, valid := range position.ValidMoves() {
+		piece := position.Board().Piece(valid.S1()).Type()
+		switch {
+		case pieceLetter(piece) != parts[1],
+			valid.S2().String() != parts[4],
+			parts[2] != "" && valid.S1().File().String() != parts[2],
+			parts[3] != "" && valid.S1().Rank().String() != parts[3],
+			pieceLetter(valid.Promo()) != parts[5]:
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("move %q is ambiguous", move)
+		}
+		found = valid
+	}
+	if found == nil {
+		return nil, fmt.Errorf("move %q is not valid", move)
+	}
+	return found, nil
+}
+
+// SameMove tells if two moves go from and to the same squares with the same promotion
+func SameMove(a, b *chess.Move) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.S1() == b.S1() && a.S2() == b.S2() && a.Promo() == b.Promo()
+}
+
+// pieceLetter is the letter of the piece in algebraic notation, empty for pawns
+func pieceLetter(piece chess.PieceType) string {
+	if piece == chess.Pawn || piece == chess.NoPieceType {
+		return ""
+	}
+	return strings.ToUpper(piece.String())
+}
@@ -0,0 +1,83 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// position returns the position of the FEN
+func position(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	option, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chess.NewGame(option).Position()
+}
+
+func TestDecodeMove(t *testing.T) {
+	// both rooks can reach d1
+	position := position(t, "4k3/8/8/6N1/8/8/4PK2/R6R w - - 0 1")
+
+	tests := []struct {
+		move string
+		// want is the move in UCI notation, empty when it can't be read
+		want string
+	}{
+		{"e4", "e2e4"},
+		{"e2e4", "e2e4"},
+		{"Nf3", "g5f3"},
+		{"Ngf3", "g5f3"},
+		{"Ng5f3", "g5f3"},
+		{"g5f3", "g5f3"},
+		{"Rad1", "a1d1"},
+		{"Ra1d1", "a1d1"},
+		{"Bd3", ""},
+		{"Rd1", ""},
+		{"e2-e4", ""},
+		{"Ke2", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			move, err := game.DecodeMove(position, tt.move)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("got %s, want an error", move)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := move.String(); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameMove(t *testing.T) {
+	position := position(t, "4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	queen, err := game.DecodeMove(position, "a8=Q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	knight, err := game.DecodeMove(position, "a7a8n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := game.DecodeMove(position, "a7a8q"); !game.SameMove(queen, again) {
+		t.Fatalf("got %s and %s, want the same move", queen, again)
+	}
+	if game.SameMove(queen, knight) {
+		t.Fatalf("got %s and %s as the same move, want the promotions told apart", queen, knight)
+	}
+	if game.SameMove(queen, nil) {
+		t.Fatal("got a move the same as no move")
+	}
+}
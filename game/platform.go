@@ -0,0 +1,28 @@
+package game
+
+// PlatformStore keeps an active game of its own in memory but saves finished games to another store.
+// Every chat platform plays its own game this way while statistics and history count them all
+type PlatformStore struct {
+	*MemoryStore
+	records ChessStorage
+}
+
+// NewPlatformStore returns a PlatformStore sharing the records of the given store
+func NewPlatformStore(records ChessStorage) *PlatformStore {
+	return &PlatformStore{MemoryStore: NewMemoryStore(), records: records}
+}
+
+// SaveRecord saves the summary of a finished game in the shared store
+func (p *PlatformStore) SaveRecord(record Record) error {
+	return p.records.SaveRecord(record)
+}
+
+// Records returns the summaries of every finished game in the shared store
+func (p *PlatformStore) Records() ([]Record, error) {
+	return p.records.Records()
+}
+
+// Record returns the summary of a finished game in the shared store
+func (p *PlatformStore) Record(id string) (Record, error) {
+	return p.records.Record(id)
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Result is the outcome of a finished game from the human players' side
+type Result string
+
+// Results of a game against the bot
+const (
+	Win  Result = "win"
+	Draw Result = "draw"
+	Loss Result = "loss"
+	// Abandoned games were removed after nobody voted for a while
+	Abandoned Result = "abandoned"
+)
+
+// Record is the summary of a finished game that is kept after the game is removed
+type Record struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	HumanColor Color     `json:"human_color"`
+	Result     Result    `json:"result"`
+	Method     string    `json:"method"`
+	// Participants are the IDs of the player who started the game and everybody who voted
+	Participants []string `json:"participants"`
+	// Moves are the moves of the game in UCI notation (e2e4, e7e8q, etc)
+	Moves       []string    `json:"moves"`
+	PGN         string      `json:"pgn"`
+	VoteHistory []VoteRound `json:"vote_history"`
+}
+
+// NewRecord summarizes a finished game, a game without an outcome is recorded as abandoned
+func NewRecord(g *Game) Record {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	humanColor := g.HumanColor()
+
+	result := Abandoned
+	method := ""
+	switch g.game.Outcome() {
+	case chess.Draw:
+		result = Draw
+	case chess.WhiteWon:
+		result = Loss
+		if humanColor == White {
+			result = Win
+		}
+	case chess.BlackWon:
+		result = Loss
+		if humanColor == Black {
+			result = Win
+		}
+	}
+	if result != Abandoned {
+		method = g.game.Method().String()
+	}
+
+	participants := []string{g.Players[humanColor].ID}
+	seen := map[string]bool{g.Players[humanColor].ID: true}
+	for _, round := range g.voteHistory {
+		for playerID := range round.Votes {
+			if !seen[playerID] {
+				seen[playerID] = true
+				participants = append(participants, playerID)
+			}
+		}
+	}
+
+	var moves []string
+	position := chess.StartingPosition()
+	for _, move := range g.game.Moves() {
+		moves = append(moves, chess.UCINotation{}.Encode(position, move))
+		position = position.Update(move)
+	}
+
+	return Record{
+		ID:           g.ID,
+		StartedAt:    g.createdAt,
+		EndedAt:      g.timeProvider(),
+		HumanColor:   humanColor,
+		Result:       result,
+		Method:       method,
+		Participants: participants,
+		Moves:        moves,
+		PGN:          strings.TrimSpace(g.game.String()),
+		VoteHistory:  append([]VoteRound(nil), g.voteHistory...),
+	}
+}
+
+// SANMoves returns the moves of the game in algebraic notation
+func (r Record) SANMoves() ([]string, error) {
+	moves, positions, err := r.replay()
+	if err != nil {
+		return nil, err
+	}
+	san := make([]string, len(moves))
+	for i, move := range moves {
+		san[i] = chess.AlgebraicNotation{}.Encode(positions[i], move)
+	}
+	return san, nil
+}
+
+// FEN returns the final position of the game
+func (r Record) FEN() (string, error) {
+	_, positions, err := r.replay()
+	if err != nil {
+		return "", err
+	}
+	return positions[len(positions)-1].String(), nil
+}
+
+// Positions returns every position of the game, starting with the initial one
+func (r Record) Positions() ([]*chess.Position, error) {
+	_, positions, err := r.replay()
+	return positions, err
+}
+
+// replay plays the moves of the record from the starting position.
+// It returns the moves along with every position of the game, starting with the initial one
+func (r Record) replay() ([]*chess.Move, []*chess.Position, error) {
+	moves := make([]*chess.Move, 0, len(r.Moves))
+	positions := []*chess.Position{chess.StartingPosition()}
+	for _, uci := range r.Moves {
+		position := positions[len(positions)-1]
+		decoded, err := chess.UCINotation{}.Decode(position, uci)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid move %q", uci)
+		}
+		// the valid moves carry the check tags that decoding on its own leaves out
+		var move *chess.Move
+		for _, valid := range position.ValidMoves() {
+			if valid.S1() == decoded.S1() && valid.S2() == decoded.S2() && valid.Promo() == decoded.Promo() {
+				move = valid
+				break
+			}
+		}
+		if move == nil {
+			return nil, nil, fmt.Errorf("illegal move %q", uci)
+		}
+		moves = append(moves, move)
+		positions = append(positions, position.Update(move))
+	}
+	return moves, positions, nil
+}
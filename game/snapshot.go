@@ -0,0 +1,127 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Snapshot is the state of a game at a single moment. It is taken in one go under the game's lock,
+// so its fields always agree with each other, and it never changes afterwards
+// which makes it safe to hand to renderers and other goroutines while the game goes on
+type Snapshot struct {
+	ID string
+	// Position is the current position, positions are never changed by later moves
+	Position *chess.Position
+	FEN      string
+	Turn     Color
+	Players  map[Color]Player
+	// HumanColor is the piece color the human players are playing with
+	HumanColor  Color
+	Moves       []*chess.Move
+	LastMove    *chess.Move
+	LastMoveSAN string
+	// CheckedKing is the square of the king in check, NoSquare when nobody is in check
+	CheckedKing chess.Square
+	// Votes maps player IDs to the move they voted for this turn
+	Votes       map[string]string
+	VoteHistory []VoteRound
+	// Evaluation is the latest engine evaluation, nil until the engine has looked at the game
+	Evaluation *Evaluation
+	CreatedAt  time.Time
+	// LastMoveTime is when the last piece was moved
+	LastMoveTime time.Time
+	// FirstVoteTime is when the first vote of the turn was made
+	FirstVoteTime time.Time
+	// VoteDuration is how long a turn lasts after the first vote
+	VoteDuration time.Duration
+	// VoteDeadline is when the top voted move gets played, zero while nobody has voted this turn
+	VoteDeadline time.Time
+	// InactivityDeadline is when the game is stopped if nobody moves until then
+	InactivityDeadline time.Time
+	Outcome            chess.Outcome
+	Method             chess.Method
+	PGN                string
+
+	voters uniqueVoters
+}
+
+// Snapshot returns the current state of the game
+func (g *Game) Snapshot() Snapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	position := g.game.Position()
+	// see Position
+	position.ValidMoves()
+
+	s := Snapshot{
+		ID:                 g.ID,
+		Position:           position,
+		FEN:                g.game.FEN(),
+		Turn:               g.turn(),
+		Players:            make(map[Color]Player, len(g.Players)),
+		HumanColor:         g.HumanColor(),
+		Moves:              g.game.Moves(),
+		LastMove:           g.lastMove(),
+		CheckedKing:        chess.NoSquare,
+		Votes:              make(map[string]string, len(g.votes)),
+		VoteHistory:        append([]VoteRound(nil), g.voteHistory...),
+		CreatedAt:          g.createdAt,
+		LastMoveTime:       g.lastMoved,
+		FirstVoteTime:      g.firstVoted,
+		VoteDuration:       g.voteDuration,
+		InactivityDeadline: g.lastMoved.Add(InactivityTimeout),
+		Outcome:            g.game.Outcome(),
+		Method:             g.game.Method(),
+		PGN:                strings.TrimSpace(g.game.String()),
+		voters:             append(uniqueVoters(nil), g.playersVoted...),
+	}
+	for color, player := range g.Players {
+		s.Players[color] = player
+	}
+	for playerID, move := range g.votes {
+		s.Votes[playerID] = move
+	}
+	if len(g.votes) > 0 {
+		s.VoteDeadline = g.firstVoted.Add(g.voteDuration)
+	}
+	if g.eval != nil {
+		eval := *g.eval
+		s.Evaluation = &eval
+	}
+	if s.LastMove != nil {
+		positions := g.game.Positions()
+		s.LastMoveSAN = chess.AlgebraicNotation{}.Encode(positions[len(positions)-2], s.LastMove)
+		if s.LastMove.HasTag(chess.Check) {
+			s.CheckedKing = g.checkedKing()
+		}
+	}
+	return s
+}
+
+// TurnPlayer returns which player should move next
+func (s Snapshot) TurnPlayer() Player {
+	return s.Players[s.Turn]
+}
+
+// ResultText shows the outcome of the game in textual format
+func (s Snapshot) ResultText() string {
+	if s.Outcome == chess.Draw {
+		return fmt.Sprintf("Game completed. %s by %s.", s.Outcome, s.Method)
+	}
+	var winningPlayer Player
+	if s.Outcome == chess.WhiteWon {
+		winningPlayer = s.Players[White]
+	} else {
+		winningPlayer = s.Players[Black]
+	}
+
+	if winningPlayer.ID != "chessbot" {
+		return fmt.Sprintf("%s %s by %s", s.voters, s.Outcome, s.Method)
+	}
+
+	return fmt.Sprintf("I won this time :chess_pawn: Better luck next time! %s by %s", s.Outcome, s.Method)
+}
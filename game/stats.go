@@ -0,0 +1,113 @@
+package game
+
+import (
+	"sort"
+	"time"
+)
+
+// PlayerStats are the statistics of a single player across finished games
+type PlayerStats struct {
+	PlayerID string
+	Games    int
+	Wins     int
+	Draws    int
+	Losses   int
+	// Abandoned counts the games removed after nobody voted for a while
+	Abandoned int
+	Votes     int
+	// PlayedVotes counts the votes that became the played move
+	PlayedVotes int
+	// CurrentStreak is the number of games won in a row up to the latest game
+	CurrentStreak int
+	// BestStreak is the longest run of games won in a row
+	BestStreak int
+}
+
+// ComputeStats aggregates the statistics of every player who took part in the records
+// that ended after since. A zero since includes every record
+func ComputeStats(records []Record, since time.Time) map[string]*PlayerStats {
+	sorted := make([]Record, 0, len(records))
+	for _, record := range records {
+		if !record.EndedAt.Before(since) {
+			sorted = append(sorted, record)
+		}
+	}
+	// streaks only make sense in the order the games were played
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].EndedAt.Before(sorted[j].EndedAt)
+	})
+
+	stats := make(map[string]*PlayerStats)
+	player := func(playerID string) *PlayerStats {
+		if _, ok := stats[playerID]; !ok {
+			stats[playerID] = &PlayerStats{PlayerID: playerID}
+		}
+		return stats[playerID]
+	}
+
+	for _, record := range sorted {
+		for _, playerID := range record.Participants {
+			ps := player(playerID)
+			ps.Games++
+			switch record.Result {
+			case Win:
+				ps.Wins++
+				ps.CurrentStreak++
+				if ps.CurrentStreak > ps.BestStreak {
+					ps.BestStreak = ps.CurrentStreak
+				}
+			case Draw:
+				ps.Draws++
+				ps.CurrentStreak = 0
+			case Loss:
+				ps.Losses++
+				ps.CurrentStreak = 0
+			case Abandoned:
+				ps.Abandoned++
+				ps.CurrentStreak = 0
+			}
+		}
+
+		// votes are compared as moves rather than text so Nf3, Ngf3 and g1f3 count as the same vote
+		moves, positions, err := record.replay()
+		for _, round := range record.VoteHistory {
+			for playerID, vote := range round.Votes {
+				ps := player(playerID)
+				ps.Votes++
+				// records that can't be replayed fall back to comparing the text
+				if err != nil || round.Ply >= len(moves) {
+					if vote == round.Played {
+						ps.PlayedVotes++
+					}
+					continue
+				}
+				if move, err := DecodeMove(positions[round.Ply], vote); err == nil && SameMove(move, moves[round.Ply]) {
+					ps.PlayedVotes++
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// Leaderboard orders the players by wins, then by games played, then by votes that became the played move
+func Leaderboard(stats map[string]*PlayerStats) []PlayerStats {
+	board := make([]PlayerStats, 0, len(stats))
+	for _, ps := range stats {
+		board = append(board, *ps)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		a, b := board[i], board[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		if a.PlayedVotes != b.PlayedVotes {
+			return a.PlayedVotes > b.PlayedVotes
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	return board
+}
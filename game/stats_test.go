@@ -0,0 +1,134 @@
+package game_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+// statsRecords are five games of U1 and U2, given out of order. U1 wins two games in a row, loses
+// one and wins again, U2 wins the first game and abandons the last one
+func statsRecords(start time.Time) []game.Record {
+	return []game.Record{
+		{ID: "3", EndedAt: start.Add(3 * time.Hour), Result: game.Loss, Participants: []string{"U1"}},
+		{
+			ID: "1", EndedAt: start.Add(time.Hour), Result: game.Win, Participants: []string{"U1", "U2"},
+			Moves: []string{"e2e4", "e7e5", "g1f3"},
+			VoteHistory: []game.VoteRound{
+				{Ply: 0, Votes: map[string]string{"U1": "e4", "U2": "d4"}, Played: "e4"},
+				// the same move written in three ways
+				{Ply: 2, Votes: map[string]string{"U1": "Ngf3", "U2": "g1f3"}, Played: "Nf3"},
+			},
+		},
+		{ID: "2", EndedAt: start.Add(2 * time.Hour), Result: game.Win, Participants: []string{"U1"}},
+		{ID: "4", EndedAt: start.Add(4 * time.Hour), Result: game.Win, Participants: []string{"U1"}},
+		{ID: "5", EndedAt: start.Add(5 * time.Hour), Result: game.Abandoned, Participants: []string{"U2"}},
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := statsRecords(start)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  map[string]game.PlayerStats
+	}{
+		{
+			name: "all time",
+			want: map[string]game.PlayerStats{
+				"U1": {PlayerID: "U1", Games: 4, Wins: 3, Losses: 1, Votes: 2, PlayedVotes: 2, CurrentStreak: 1, BestStreak: 2},
+				"U2": {PlayerID: "U2", Games: 2, Wins: 1, Abandoned: 1, Votes: 2, PlayedVotes: 1, BestStreak: 1},
+			},
+		},
+		{
+			name:  "since the second game",
+			since: start.Add(2 * time.Hour),
+			want: map[string]game.PlayerStats{
+				"U1": {PlayerID: "U1", Games: 3, Wins: 2, Losses: 1, CurrentStreak: 1, BestStreak: 1},
+				"U2": {PlayerID: "U2", Games: 1, Abandoned: 1},
+			},
+		},
+		{
+			name:  "after the last game",
+			since: start.Add(6 * time.Hour),
+			want:  map[string]game.PlayerStats{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := game.ComputeStats(records, tt.since)
+			if len(stats) != len(tt.want) {
+				t.Fatalf("got stats for %d players, want %d", len(stats), len(tt.want))
+			}
+			for playerID, want := range tt.want {
+				got, ok := stats[playerID]
+				if !ok {
+					t.Fatalf("got no stats for %s", playerID)
+				}
+				if *got != want {
+					t.Fatalf("got %+v, want %+v", *got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeStatsComparesUnreadableRecordsAsText(t *testing.T) {
+	records := []game.Record{{
+		Result:       game.Win,
+		Participants: []string{"U1"},
+		Moves:        []string{"e2e5"},
+		VoteHistory:  []game.VoteRound{{Ply: 0, Votes: map[string]string{"U1": "e4", "U2": "e2e4"}, Played: "e4"}},
+	}}
+	stats := game.ComputeStats(records, time.Time{})
+	if stats["U1"].PlayedVotes != 1 || stats["U2"].PlayedVotes != 0 {
+		t.Fatalf("got %+v and %+v, want only the vote written like the played move to count", *stats["U1"], *stats["U2"])
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	stats := map[string]*game.PlayerStats{
+		"U1": {PlayerID: "U1", Games: 3, Wins: 1, PlayedVotes: 5},
+		"U2": {PlayerID: "U2", Games: 2, Wins: 2},
+		"U3": {PlayerID: "U3", Games: 4, Wins: 1},
+		"U4": {PlayerID: "U4", Games: 3, Wins: 1, PlayedVotes: 7},
+		"U5": {PlayerID: "U5", Games: 3, Wins: 1, PlayedVotes: 7},
+	}
+
+	board := game.Leaderboard(stats)
+	// wins first, then games, then played votes, then the ID
+	want := []string{"U2", "U3", "U4", "U5", "U1"}
+	if len(board) != len(want) {
+		t.Fatalf("got %+v, want %v", board, want)
+	}
+	for i, ps := range board {
+		if ps.PlayerID != want[i] {
+			t.Fatalf("got %s at %d, want %s", ps.PlayerID, i+1, want[i])
+		}
+	}
+}
+
+func TestNewRecordOfAnAbandonedGame(t *testing.T) {
+	g := newGame()
+	if err := g.Vote("U2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.MoveTopVote(); err != nil {
+		t.Fatal(err)
+	}
+
+	record := game.NewRecord(g)
+	if record.Result != game.Abandoned || record.Method != "" {
+		t.Fatalf("got the result %q by %q, want an abandoned game", record.Result, record.Method)
+	}
+	if len(record.Participants) != 2 || record.Participants[1] != "U2" {
+		t.Fatalf("got the participants %v, want the voter kept", record.Participants)
+	}
+	if len(record.Moves) != 1 || record.Moves[0] != "e2e4" {
+		t.Fatalf("got the moves %v, want e2e4", record.Moves)
+	}
+}
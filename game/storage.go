@@ -3,6 +3,14 @@ package game
 // ChessStorage is an interface to persist a game
 type ChessStorage interface {
 	RetrieveGame() (*Game, error)
+	// StoreGame makes the game the active one. It fails when there already is an active game,
+	// so of two games started at once only one gets stored
 	StoreGame(game *Game) error
 	RemoveGame() error
+	// SaveRecord keeps the summary of a finished game
+	SaveRecord(record Record) error
+	// Records returns the summaries of every finished game
+	Records() ([]Record, error)
+	// Record returns the summary of the finished game with the given ID
+	Record(id string) (Record, error)
 }
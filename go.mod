@@ -1,14 +1,19 @@
 module github.com/dyslexicat/collab-chess
 
-go 1.15
+go 1.18
 
 require (
-	github.com/cjsaylor/chessimage v0.0.0-20190107020940-8abad33612f4
-	github.com/flopp/go-findfont v0.0.0-20201114153133-e7393a00c15b // indirect
-	github.com/fogleman/gg v1.3.0 // indirect
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/bwmarrin/discordgo v0.27.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.3.0
 	github.com/nlopes/slack v0.6.0
 	github.com/notnil/chess v1.5.0
-	golang.org/x/image v0.0.0-20210216034530-4410531fe030 // indirect
+	golang.org/x/image v0.0.0-20210216034530-4410531fe030
+)
+
+require (
+	github.com/pkg/errors v0.8.0 // indirect
+	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b // indirect
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68 // indirect
+	golang.org/x/text v0.3.3 // indirect
 )
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/analysis"
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// worstMovesShown is the number of moves listed in the analysis summary
+const worstMovesShown = 3
+
+// leaderboardSize is the number of voters listed in the leaderboard
+const leaderboardSize = 10
+
+// postAnalysis runs the engine over a finished game and posts how well the human players
+// and every single voter did
+func (b Bot) postAnalysis(moves []*chess.Move, rounds []game.VoteRound, humanColor game.Color) {
+	if b.AnalysisMoveTime <= 0 || len(moves) == 0 {
+		return
+	}
+
+	newEngine := b.NewAnalysisEngine
+	if newEngine == nil {
+		newEngine = func() (Engine, error) {
+			return analysis.NewStockfish("stockfish", b.AnalysisMoveTime)
+		}
+	}
+	eng, err := newEngine()
+	if err != nil {
+		log.Println("could not start the analysis engine:", err)
+		return
+	}
+	analyzer := analysis.New(eng)
+	defer analyzer.Close()
+
+	report, err := analyzer.Analyze(moves, humanColor)
+	if err != nil {
+		log.Println("could not analyze the game:", err)
+		return
+	}
+
+	b.Chat.PostText(b.GameChannel, analysisSummary(report))
+
+	if voters := analysis.Voters(moves, rounds, report); len(voters) > 0 {
+		b.Chat.PostText(b.GameChannel, voterLeaderboard(voters))
+	}
+}
+
+// voterLeaderboard formats how often each voter picked the engine's move and the crowd's move
+func voterLeaderboard(voters []analysis.VoterStats) string {
+	var b strings.Builder
+	b.WriteString(":trophy: *Who thought like the engine?*\n")
+	for i, voter := range voters {
+		if i == leaderboardSize {
+			break
+		}
+		fmt.Fprintf(&b, "%d. <@%s> picked the engine's move %d/%d times (%.0f%%) and the played move %d/%d times (%.0f%%)\n",
+			i+1, voter.PlayerID,
+			voter.EngineMatches, voter.Votes, voter.EngineAgreement(),
+			voter.CrowdMatches, voter.Votes, voter.CrowdAgreement())
+	}
+	return b.String()
+}
+
+// analysisSummary formats the report of the human players' moves
+func analysisSummary(report analysis.Report) string {
+	var b strings.Builder
+	b.WriteString(":mag: *Game analysis*\n")
+	fmt.Fprintf(&b, "Your accuracy: *%.1f%%* with %s, %s and %s\n",
+		report.Accuracy,
+		plural(report.Blunders, "blunder"),
+		plural(report.Mistakes, "mistake"),
+		plural(report.Inaccuracies, "inaccuracy"))
+
+	worst := report.Worst(worstMovesShown)
+	if len(worst) == 0 {
+		b.WriteString("Not a single slip, well played! :clap:")
+		return b.String()
+	}
+
+	b.WriteString("Moves to look at again:\n")
+	for _, move := range worst {
+		fmt.Fprintf(&b, "• *%s* was a %s (%s → %s)", move.Notation(), move.Classification, move.Before, move.After)
+		if move.Best != "" {
+			fmt.Fprintf(&b, ", better was *%s*", move.Best)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// plural formats a count with its noun, like 1 blunder or 2 mistakes
+func plural(n int, noun string) string {
+	return fmt.Sprintf("%d %s", n, pluralNoun(n, noun))
+}
+
+// pluralNoun returns the noun in its plural form unless n is one
+func pluralNoun(n int, noun string) string {
+	switch {
+	case n == 1:
+		return noun
+	case strings.HasSuffix(noun, "y"):
+		return strings.TrimSuffix(noun, "y") + "ies"
+	default:
+		return noun + "s"
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/analysis"
+	"github.com/dyslexicat/collab-chess/chat/chattest"
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// evenEngine sees every position as even and suggests h4 whenever it can be played
+type evenEngine struct{}
+
+func (evenEngine) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	move, _ := chess.AlgebraicNotation{}.Decode(position, "h4")
+	return move, game.Evaluation{}, nil
+}
+
+func (evenEngine) Close() error {
+	return nil
+}
+
+// playedGame returns the moves of a game given in algebraic notation
+func playedGame(t *testing.T, sans ...string) []*chess.Move {
+	t.Helper()
+	g := chess.NewGame()
+	for _, san := range sans {
+		if err := g.MoveStr(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g.Moves()
+}
+
+func TestPostAnalysisUsesTheAnalysisEngine(t *testing.T) {
+	fake := chattest.NewFake(nil)
+	var started int
+	bot := Bot{
+		Chat:             fake,
+		GameChannel:      testChannel,
+		AnalysisMoveTime: time.Millisecond,
+		NewAnalysisEngine: func() (Engine, error) {
+			started++
+			return evenEngine{}, nil
+		},
+	}
+	moves := playedGame(t, "a3", "e5", "b3")
+	rounds := []game.VoteRound{
+		{Ply: 0, Votes: map[string]string{"U1": "a3"}, Played: "a3"},
+		{Ply: 2, Votes: map[string]string{"U1": "b3"}, Played: "b3"},
+	}
+
+	bot.postAnalysis(moves, rounds, game.White)
+	if started != 1 {
+		t.Fatalf("the analysis engine was started %d times, want once", started)
+	}
+	posts := fake.Posts()
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want the analysis and the leaderboard: %+v", len(posts), posts)
+	}
+	// no move loses anything when every position is even, even though none of them was h4
+	if want := "Your accuracy: *100.0%* with 0 blunders, 0 mistakes and 0 inaccuracies"; !strings.Contains(posts[0].Text, want) {
+		t.Fatalf("got %q, want %q", posts[0].Text, want)
+	}
+	if !strings.Contains(posts[1].Text, "<@U1> picked the engine's move 0/2 times (0%) and the played move 2/2 times (100%)") {
+		t.Fatalf("got %q", posts[1].Text)
+	}
+
+	// a zero move time turns the analysis off
+	fake.Reset()
+	bot.AnalysisMoveTime = 0
+	bot.postAnalysis(moves, rounds, game.White)
+	if posts := fake.Posts(); len(posts) != 0 || started != 1 {
+		t.Fatalf("got %+v, want no analysis", posts)
+	}
+}
+
+func TestVoterLeaderboard(t *testing.T) {
+	var many []analysis.VoterStats
+	for i := 0; i < leaderboardSize+2; i++ {
+		many = append(many, analysis.VoterStats{PlayerID: fmt.Sprintf("U%d", i), Votes: 1})
+	}
+
+	tests := []struct {
+		name   string
+		voters []analysis.VoterStats
+		want   []string
+	}{
+		{
+			name: "agreement as counts and shares",
+			voters: []analysis.VoterStats{
+				{PlayerID: "U1", Votes: 3, EngineMatches: 2, CrowdMatches: 3},
+				{PlayerID: "api:bob", Votes: 4, EngineMatches: 1},
+			},
+			want: []string{
+				":trophy: *Who thought like the engine?*",
+				"1. <@U1> picked the engine's move 2/3 times (67%) and the played move 3/3 times (100%)",
+				"2. <@api:bob> picked the engine's move 1/4 times (25%) and the played move 0/4 times (0%)",
+			},
+		},
+		{
+			name:   "only the top voters",
+			voters: many,
+			want: func() []string {
+				lines := []string{":trophy: *Who thought like the engine?*"}
+				for i := 0; i < leaderboardSize; i++ {
+					lines = append(lines, fmt.Sprintf("%d. <@U%d> picked the engine's move 0/1 times (0%%) and the played move 0/1 times (0%%)", i+1, i))
+				}
+				return lines
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := voterLeaderboard(tt.voters)
+			if want := strings.Join(tt.want, "\n") + "\n"; got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Engine picks the moves of the bot
+type Engine interface {
+	// Move returns the move to play in the position along with its evaluation from White's point of view.
+	// The line of the evaluation starts with the returned move
+	Move(position *chess.Position) (*chess.Move, game.Evaluation, error)
+	Close() error
+}
+
+// stockfish plays with a limited strength Stockfish found in the PATH
+type stockfish struct {
+	eng *uci.Engine
+}
+
+// NewStockfish starts the engine the bot plays with unless Bot.NewEngine says otherwise
+func NewStockfish() (Engine, error) {
+	eng, err := uci.New("stockfish")
+	if err != nil {
+		return nil, err
+	}
+
+	setOpt := uci.CmdSetOption{Name: "UCI_LimitStrength", Value: "true"}
+
+	// initialize uci with new game
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setOpt, uci.CmdUCINewGame); err != nil {
+		eng.Close()
+		return nil, err
+	}
+	return stockfish{eng: eng}, nil
+}
+
+func (s stockfish) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	cmdPos := uci.CmdPosition{Position: position}
+
+	// thinkingTime is a value between 10 and 60
+	// to simulate the thinking time of our bot so that we get different moves
+	thinkingTime := time.Duration(rand.Intn(51) + 10)
+
+	cmdGo := uci.CmdGo{MoveTime: 2 * time.Second / thinkingTime}
+	if err := s.eng.Run(cmdPos, cmdGo); err != nil {
+		return nil, game.Evaluation{}, err
+	}
+	results := s.eng.SearchResults()
+
+	// the engine scores from its own point of view, evaluations are stored from White's
+	eval := game.Evaluation{CP: results.Info.Score.CP, Mate: results.Info.Score.Mate, Line: results.Info.PV}
+	if position.Turn() == chess.Black {
+		eval.CP, eval.Mate = -eval.CP, -eval.Mate
+	}
+	return results.BestMove, eval, nil
+}
+
+func (s stockfish) Close() error {
+	return s.eng.Close()
+}
@@ -1,185 +1,260 @@
 package handler
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"math/rand"
-	"net/http"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/dyslexicat/collab-chess/chat"
 	"github.com/dyslexicat/collab-chess/game"
 	"github.com/dyslexicat/collab-chess/rendering"
 
-	"github.com/nlopes/slack"
-	"github.com/nlopes/slack/slackevents"
 	"github.com/notnil/chess"
-	"github.com/notnil/chess/uci"
 )
 
-// SlackHandler handles Slack events
-type SlackHandler struct {
-	SigningKey   string
-	BotToken     string
-	SlackClient  *slack.Client
+// Bot plays chess with the members of a chat channel, whatever the chat platform is
+type Bot struct {
+	Chat         chat.Client
 	GameStorage  game.ChessStorage
 	LinkRenderer rendering.RenderLink
 	GameChannel  string
+	// UploadImages renders boards in-process and uploads them
+	// instead of linking to the public /board.png endpoint
+	UploadImages bool
+	Settings     *ChannelSettings
+	// ReplayDelay is how long each position is shown for in game replays
+	ReplayDelay time.Duration
+	// ShowEvaluation draws the engine's evaluation bar next to the posted boards
+	ShowEvaluation bool
+	// AnalysisMoveTime is how long the engine looks at every position of a finished game, zero disables the analysis
+	AnalysisMoveTime time.Duration
+	// NewEngine starts the engine the bot plays with, Stockfish when nil
+	NewEngine func() (Engine, error)
+	// NewAnalysisEngine starts the engine finished games are analyzed with,
+	// a full strength Stockfish searching every position for AnalysisMoveTime when nil
+	NewAnalysisEngine func() (Engine, error)
+	// VoteDuration is how long a turn of new games lasts after the first vote, game.VoteDuration when zero
+	VoteDuration time.Duration
 }
 
+// countdownWarning is how long before the end of a turn the channel gets a reminder
+const countdownWarning = 10 * time.Second
+
+// engineLineMoves is how many moves of the engine's expected continuation are drawn after its move
+const engineLineMoves = 2
+
 var colorToHex = map[game.Color]string{
 	game.Black: "#000000",
 	game.White: "#eeeeee",
 }
 
-func (s SlackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// postBoard posts a message to the channel along with an image of the board in the snapshot
+func (b Bot) postBoard(channel string, text string, snapshot game.Snapshot, options ...rendering.Option) {
+	options = append([]rendering.Option{rendering.WithTheme(b.Settings.Theme(channel))}, options...)
+	if snapshot.Evaluation != nil && b.ShowEvaluation {
+		options = append(options, rendering.WithEvaluation(*snapshot.Evaluation))
 	}
 
-	sv, err := slack.NewSecretsVerifier(r.Header, s.SigningKey)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	alt := rendering.TextBoard(snapshot, options...)
+
+	if b.UploadImages {
+		image, err := rendering.RenderPNG(snapshot, options...)
+		if err != nil {
+			log.Println("could not render the board:", err)
+			b.Chat.PostText(channel, text)
+			return
+		}
+
+		if err := b.Chat.PostImage(channel, text, chat.Image{Name: "board.png", Data: image, Alt: alt}); err != nil {
+			log.Println("could not upload the board:", err)
+		}
 		return
 	}
-	if _, err := sv.Write(body); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+
+	link, _ := b.LinkRenderer.CreateLink(snapshot, options...)
+	b.Chat.PostImage(channel, text, chat.Image{URL: link.String(), Color: colorToHex[snapshot.Turn], Alt: alt})
+}
+
+// voteSummary lists the voted moves with their vote counts, most voted first
+func voteSummary(votes map[string]string) string {
+	freqs := make(map[string]int)
+	for _, move := range votes {
+		freqs[move]++
 	}
-	if err := sv.Ensure(); err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+
+	moves := make([]string, 0, len(freqs))
+	for move := range freqs {
+		moves = append(moves, move)
 	}
-	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	sort.Slice(moves, func(i, j int) bool {
+		if freqs[moves[i]] != freqs[moves[j]] {
+			return freqs[moves[i]] > freqs[moves[j]]
+		}
+		return moves[i] < moves[j]
+	})
+
+	tally := make([]string, len(moves))
+	for i, move := range moves {
+		noun := "votes"
+		if freqs[move] == 1 {
+			noun = "vote"
+		}
+		tally[i] = fmt.Sprintf("*%s* (%d %s)", move, freqs[move], noun)
 	}
+	return "Votes so far: " + strings.Join(tally, ", ")
+}
 
-	if eventsAPIEvent.Type == slackevents.URLVerification {
-		var r *slackevents.ChallengeResponse
-		err := json.Unmarshal([]byte(body), &r)
+// postReplay posts a message to the channel along with an animated replay of the game so far
+func (b Bot) postReplay(channel string, text string, snapshot game.Snapshot) {
+	theme := rendering.WithTheme(b.Settings.Theme(channel))
+	delay := b.ReplayDelay
+	if delay == 0 {
+		delay = rendering.DefaultReplayDelay
+	}
+
+	if b.UploadImages {
+		image, err := rendering.RenderGIF(snapshot, delay, theme)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			log.Println("could not render the replay:", err)
 			return
 		}
-		w.Header().Set("Content-Type", "text")
-		w.Write([]byte(r.Challenge))
-	}
-	if eventsAPIEvent.Type == slackevents.CallbackEvent {
-		if s.SlackClient == nil {
-			s.SlackClient = slack.New(s.BotToken)
+
+		if err := b.Chat.PostImage(channel, text, chat.Image{Name: "replay.gif", Data: image}); err != nil {
+			log.Println("could not upload the replay:", err)
 		}
+		return
+	}
 
-		innerEvent := eventsAPIEvent.InnerEvent
-		switch ev := innerEvent.Data.(type) {
-		case *slackevents.AppMentionEvent:
-			s.SlackClient.PostMessage(ev.Channel, slack.MsgOptionText("Hi! I live in #playchess at Hack Club. !help to get help on how to play. You can type !start to start a game of chess, !move [notation] (for example, !move e4 or !move Nc6) to vote on a move. !board shows the current state of the board. Each turn top voted move gets played. If no votes are present after 8 mins, current game stops. Good luck! :chess_pawn:", false))
-		case *slackevents.MessageEvent:
-			msg := parseMessage(ev)
-			if msg == nil {
-				return
-			}
+	link, _ := b.LinkRenderer.CreateReplayLink(snapshot, delay, theme)
+	b.Chat.PostImage(channel, text, chat.Image{URL: link.String()})
+}
 
-			msg.Handle(&s)
-		}
+// introText is posted when somebody mentions the bot
+const introText = "Hi! I live in #playchess at Hack Club. !help to get help on how to play. You can type !start to start a game of chess, !move [notation] (for example, !move e4 or !move Nc6) to vote on a move. !board shows the current state of the board. !theme [name] changes how the board looks. !replay animates the game so far. !votes shows the votes of the current turn. !stats and !leaderboard show who played best. Each turn top voted move gets played. If no votes are present after 8 mins, current game stops. Good luck! :chess_pawn:"
+
+// HandleMessage answers mentions with an introduction and handles the commands of every other message
+func (b Bot) HandleMessage(m chat.Message) {
+	if m.Mention {
+		b.Chat.PostText(m.ChannelID, introText)
+		return
+	}
+
+	msg := parseMessage(m)
+	if msg == nil {
+		return
 	}
+
+	msg.Handle(&b)
 }
 
 // GameLoop is the main loop where the game starts and checks for moves between players
-func (s SlackHandler) GameLoop() {
-	// set up engine to use stockfish exe
-	eng, err := uci.New("stockfish")
+func (b Bot) GameLoop() {
+	newEngine := b.NewEngine
+	if newEngine == nil {
+		newEngine = NewStockfish
+	}
+	eng, err := newEngine()
 	if err != nil {
 		panic(err)
 	}
 
 	defer eng.Close()
 
-	setOpt := uci.CmdSetOption{Name: "UCI_LimitStrength", Value: "true"}
-
-	// initialize uci with new game
-	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setOpt, uci.CmdUCINewGame); err != nil {
-		panic(err)
-	}
+	// the first vote time of the turn we last sent a countdown warning for
+	var warnedTurn time.Time
 
 	func() {
 		for {
 			time.Sleep(time.Second)
 
-			gm, err := s.GameStorage.RetrieveGame()
+			gm, err := b.GameStorage.RetrieveGame()
 
 			if err != nil {
 				return
 			}
 
-			if outcome := gm.Outcome(); outcome != chess.NoOutcome {
-				link, _ := s.LinkRenderer.CreateLink(gm)
+			// decisions are made on a snapshot so votes coming in meanwhile can't change the state halfway
+			snapshot := gm.Snapshot()
 
-				boardAttachment := slack.Attachment{
-					ImageURL: link.String(),
-					Color:    colorToHex[gm.Turn()],
+			if snapshot.Outcome != chess.NoOutcome {
+				b.postBoard(b.GameChannel, snapshot.ResultText(), snapshot)
+				b.postReplay(b.GameChannel, "Here is how the game went :film_projector:", snapshot)
+				if err := b.GameStorage.SaveRecord(game.NewRecord(gm)); err != nil {
+					log.Println("could not save the game record:", err)
 				}
-
-				s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText(gm.ResultText(), false), slack.MsgOptionAttachments(boardAttachment))
-				s.GameStorage.RemoveGame()
+				b.GameStorage.RemoveGame()
+				b.postAnalysis(snapshot.Moves, snapshot.VoteHistory, snapshot.HumanColor)
 				return
 			}
 
-			if gm.TurnPlayer().ID == "chessbot" {
-				gm.Lock()
-				cmdPos := uci.CmdPosition{Position: gm.Position()}
-
-				// thinkingTime is a value between 10 and 60
-				// to simulate the thinking time of our bot so that we get different moves
-				thinkingTime := time.Duration(rand.Intn(51) + 10)
-
-				cmdGo := uci.CmdGo{MoveTime: 2 * time.Second / thinkingTime}
-				if err := eng.Run(cmdPos, cmdGo); err != nil {
+			if snapshot.TurnPlayer().ID == "chessbot" {
+				move, eval, err := eng.Move(snapshot.Position)
+				if err != nil {
 					panic(err)
 				}
-				move := eng.SearchResults().BestMove
-				gm.Unlock()
 				if err := gm.BotMove(move); err != nil {
-					panic(err)
+					// the position changed since the snapshot, the next round looks again
+					log.Println("could not play the bot move", move, err)
+					continue
+				}
+				// the line starts with the move just played, what is left is the continuation
+				if len(eval.Line) > 0 {
+					eval.Line = eval.Line[1:]
 				}
+				gm.SetEvaluation(eval)
 
-				if outcome := gm.Outcome(); outcome != chess.NoOutcome {
+				snapshot = gm.Snapshot()
+				if snapshot.Outcome != chess.NoOutcome {
 					continue
 				}
 
-				link, _ := s.LinkRenderer.CreateLink(gm)
-
-				boardAttachment := slack.Attachment{
-					ImageURL: link.String(),
-					Color:    colorToHex[gm.Turn()],
+				var options []rendering.Option
+				if b.ShowEvaluation && snapshot.Evaluation != nil {
+					line := snapshot.Evaluation.Line
+					if len(line) > engineLineMoves {
+						line = line[:engineLineMoves]
+					}
+					options = append(options, rendering.WithArrows(line...))
 				}
-
-				s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText("I made my move :crossed_swords:", false), slack.MsgOptionAttachments(boardAttachment))
+				b.postBoard(b.GameChannel, "I made my move :crossed_swords:", snapshot, options...)
 			}
 
-			if gm.TurnPlayer().ID != "chessbot" {
-				if time.Since(gm.LastMoveTime()) > 8*time.Minute {
-					log.Println("nobody made a move :( removing the current game from pool")
-					s.GameStorage.RemoveGame()
-
-					s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText("Nobody made a move in a while :( Stopping the current game. You can start a new game by typing *!start*", false))
+			if snapshot.TurnPlayer().ID != "chessbot" {
+				if time.Since(snapshot.LastMoveTime) > game.InactivityTimeout {
+					b.stopInactiveGame(gm)
 					return
 				}
 
-				if time.Since(gm.FirstVoteTime()) > 40*time.Second {
+				if len(snapshot.Votes) > 0 && snapshot.VoteDuration > countdownWarning && time.Since(snapshot.FirstVoteTime) > snapshot.VoteDuration-countdownWarning && !warnedTurn.Equal(snapshot.FirstVoteTime) {
+					warnedTurn = snapshot.FirstVoteTime
+					text := fmt.Sprintf(":hourglass_flowing_sand: %d seconds left to vote! %s", int(countdownWarning.Seconds()), voteSummary(snapshot.Votes))
+					b.postBoard(b.GameChannel, text, snapshot, rendering.WithVotes(snapshot.Votes))
+				}
+
+				if time.Since(snapshot.FirstVoteTime) > snapshot.VoteDuration {
 					topVotedMove, err := gm.MoveTopVote()
 					if err != nil {
 						continue
 					}
 
 					text := fmt.Sprintf("Top voted move was: *%s*", topVotedMove)
-					s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText(text, false))
+					b.Chat.PostText(b.GameChannel, text)
 				}
 			}
 		}
 	}()
 }
+
+// stopInactiveGame removes a game nobody moved in for a while. Its record is kept so the votes
+// and the abandoned game still count in the statistics
+func (b Bot) stopInactiveGame(gm *game.Game) {
+	log.Println("nobody made a move :( removing the current game from pool")
+	if err := b.GameStorage.SaveRecord(game.NewRecord(gm)); err != nil {
+		log.Println("could not save the game record:", err)
+	}
+	b.GameStorage.RemoveGame()
+
+	b.Chat.PostText(b.GameChannel, "Nobody made a move in a while :( Stopping the current game. You can start a new game by typing *!start*")
+}
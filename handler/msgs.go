@@ -7,10 +7,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/dyslexicat/collab-chess/chat"
 	"github.com/dyslexicat/collab-chess/game"
-
-	"github.com/nlopes/slack"
-	"github.com/nlopes/slack/slackevents"
+	"github.com/dyslexicat/collab-chess/rendering"
 )
 
 // Msg interface includes methods about important info for a slack message and handles what to do with it
@@ -18,47 +17,47 @@ type Msg interface {
 	ChannelID() string
 	Timestamp() string
 	ThreadTimestamp() string
-	Raw() *slackevents.MessageEvent
+	Raw() chat.Message
 
-	Handle(s *SlackHandler)
+	Handle(b *Bot)
 }
 
 // GameStartMsg is a struct for a message to start a new game
 type GameStartMsg struct {
 	player     string
 	pieceColor string
-	raw        *slackevents.MessageEvent
+	raw        chat.Message
 }
 
 func (m GameStartMsg) ChannelID() string {
-	return m.raw.Channel
+	return m.raw.ChannelID
 }
 
 func (m GameStartMsg) Timestamp() string {
-	return m.raw.TimeStamp
+	return m.raw.Timestamp
 }
 
 func (m GameStartMsg) ThreadTimestamp() string {
-	return m.raw.ThreadTimeStamp
+	return m.raw.ThreadID
 }
 
-func (m GameStartMsg) Raw() *slackevents.MessageEvent {
+func (m GameStartMsg) Raw() chat.Message {
 	return m.raw
 }
 
-func ParseGameStartMsg(m *slackevents.MessageEvent) (*GameStartMsg, bool) {
+func ParseGameStartMsg(m chat.Message) (*GameStartMsg, bool) {
 	// cannot be in a thread
-	if m.ThreadTimeStamp != "" {
+	if m.ThreadID != "" {
 		return nil, false
 	}
 
 	// it is in a DM
-	if strings.HasPrefix(m.Channel, "D") {
+	if m.Direct {
 		return nil, false
 	}
 
 	if m.Text == "!start" {
-		return &GameStartMsg{raw: m, player: m.User, pieceColor: ""}, true
+		return &GameStartMsg{raw: m, player: m.UserID, pieceColor: ""}, true
 	}
 
 	regex := regexp.MustCompile("^!start (.*)$")
@@ -70,9 +69,9 @@ func ParseGameStartMsg(m *slackevents.MessageEvent) (*GameStartMsg, bool) {
 
 	switch matches[1] {
 	case "white":
-		return &GameStartMsg{raw: m, player: m.User, pieceColor: "white"}, true
+		return &GameStartMsg{raw: m, player: m.UserID, pieceColor: "white"}, true
 	case "black":
-		return &GameStartMsg{raw: m, player: m.User, pieceColor: "black"}, true
+		return &GameStartMsg{raw: m, player: m.UserID, pieceColor: "black"}, true
 	default:
 		return nil, false
 	}
@@ -92,15 +91,7 @@ func randomString(len int) string {
 	return string(bytes)
 }
 
-func (msg GameStartMsg) Handle(s *SlackHandler) {
-	_, err := s.GameStorage.RetrieveGame()
-	if err == nil {
-		s.SlackClient.PostMessage(msg.ChannelID(), slack.MsgOptionText("There is already a game in place. Type *!board* to see the state of the board. Vote on a move!", false))
-		return
-	}
-
-	log.Println(msg.player, "is starting a chess game")
-
+func (msg GameStartMsg) Handle(b *Bot) {
 	// first element is the bot and the second one is the human players
 	players := []game.Player{
 		{ID: "chessbot"},
@@ -110,46 +101,55 @@ func (msg GameStartMsg) Handle(s *SlackHandler) {
 	gameID := randomString(20)
 
 	gm := game.NewGame(gameID, msg.pieceColor, players...)
-	s.GameStorage.StoreGame(gm)
+	if b.VoteDuration > 0 {
+		gm.SetVoteDuration(b.VoteDuration)
+	}
+	// storing fails when there is a game already, also when it was started at the same time
+	if err := b.GameStorage.StoreGame(gm); err != nil {
+		b.Chat.PostText(msg.ChannelID(), "There is already a game in place. Type *!board* to see the state of the board. Vote on a move!")
+		return
+	}
 
-	go s.GameLoop()
+	log.Println(msg.player, "is starting a chess game")
 
-	humanColor, err := gm.GetColor(msg.player)
+	go b.GameLoop()
+
+	humanColor, _ := gm.GetColor(msg.player)
 	text := fmt.Sprintf("Hackalackers are playing: %s", humanColor)
-	s.SlackClient.PostMessage(msg.ChannelID(), slack.MsgOptionText(text, false))
+	b.Chat.PostText(msg.ChannelID(), text)
 }
 
 // MoveMsg represents a move
 type MoveMsg struct {
 	san    string
 	player string
-	raw    *slackevents.MessageEvent
+	raw    chat.Message
 }
 
 func (m MoveMsg) ChannelID() string {
-	return m.raw.Channel
+	return m.raw.ChannelID
 }
 
 func (m MoveMsg) Timestamp() string {
-	return m.raw.TimeStamp
+	return m.raw.Timestamp
 }
 
 func (m MoveMsg) ThreadTimestamp() string {
-	return m.raw.ThreadTimeStamp
+	return m.raw.ThreadID
 }
 
-func (m MoveMsg) Raw() *slackevents.MessageEvent {
+func (m MoveMsg) Raw() chat.Message {
 	return m.raw
 }
 
-func ParseMoveMsg(m *slackevents.MessageEvent) (*MoveMsg, bool) {
+func ParseMoveMsg(m chat.Message) (*MoveMsg, bool) {
 	// cannot be in a thread
-	if m.ThreadTimeStamp != "" {
+	if m.ThreadID != "" {
 		return nil, false
 	}
 
 	// it is in a DM
-	if strings.HasPrefix(m.Channel, "D") {
+	if m.Direct {
 		return nil, false
 	}
 
@@ -161,14 +161,14 @@ func ParseMoveMsg(m *slackevents.MessageEvent) (*MoveMsg, bool) {
 
 	playerMove := matches[1]
 
-	return &MoveMsg{san: playerMove, player: m.User, raw: m}, true
+	return &MoveMsg{san: playerMove, player: m.UserID, raw: m}, true
 }
 
-func (msg MoveMsg) Handle(s *SlackHandler) {
-	gm, err := s.GameStorage.RetrieveGame()
+func (msg MoveMsg) Handle(b *Bot) {
+	gm, err := b.GameStorage.RetrieveGame()
 
 	if err != nil {
-		s.SlackClient.PostMessage(msg.ChannelID(), slack.MsgOptionText("There isn't an active game at the moment :( You can use the *!start* command to start a new game :chess_pawn: ", false))
+		b.Chat.PostText(msg.ChannelID(), "There isn't an active game at the moment :( You can use the *!start* command to start a new game :chess_pawn: ")
 		return
 	}
 
@@ -180,7 +180,8 @@ func (msg MoveMsg) Handle(s *SlackHandler) {
 	moveErr := gm.Vote(msg.player, msg.san)
 
 	if moveErr != nil {
-		fmt.Println(moveErr)
+		// only the voter needs to know that the vote didn't count
+		b.Chat.PostEphemeral(msg.ChannelID(), msg.player, fmt.Sprintf("*%s* is not a valid move right now. Type *!help* to see how to write moves", msg.san))
 		return
 	}
 }
@@ -188,109 +189,316 @@ func (msg MoveMsg) Handle(s *SlackHandler) {
 // BoardMsg represents a message to ask the current board state
 type BoardMsg struct {
 	player string
-	raw    *slackevents.MessageEvent
+	// flip shows the board from the bot's side instead of the human players' side
+	flip bool
+	// text posts a Unicode board instead of an image
+	text bool
+	raw  chat.Message
 }
 
 func (m BoardMsg) ChannelID() string {
-	return m.raw.Channel
+	return m.raw.ChannelID
 }
 
 func (m BoardMsg) Timestamp() string {
-	return m.raw.TimeStamp
+	return m.raw.Timestamp
 }
 
 func (m BoardMsg) ThreadTimestamp() string {
-	return m.raw.ThreadTimeStamp
+	return m.raw.ThreadID
 }
 
-func (m BoardMsg) Raw() *slackevents.MessageEvent {
+func (m BoardMsg) Raw() chat.Message {
 	return m.raw
 }
 
-func ParseBoardMsg(m *slackevents.MessageEvent) (*BoardMsg, bool) {
+func ParseBoardMsg(m chat.Message) (*BoardMsg, bool) {
+	// the text board works everywhere, even in threads and DMs where images are a hassle
+	if m.Text == "!board text" {
+		return &BoardMsg{raw: m, player: m.UserID, text: true}, true
+	}
+
 	// cannot be in a thread
-	if m.ThreadTimeStamp != "" {
+	if m.ThreadID != "" {
 		return nil, false
 	}
 
 	// it is in a DM
-	if strings.HasPrefix(m.Channel, "D") {
+	if m.Direct {
 		return nil, false
 	}
 
-	if m.Text == "!board" {
-		return &BoardMsg{raw: m, player: m.User}, true
+	switch m.Text {
+	case "!board":
+		return &BoardMsg{raw: m, player: m.UserID}, true
+	case "!board flip":
+		return &BoardMsg{raw: m, player: m.UserID, flip: true}, true
 	}
 
 	return nil, false
 }
 
-func (m BoardMsg) Handle(s *SlackHandler) {
-	gm, err := s.GameStorage.RetrieveGame()
+func (m BoardMsg) Handle(b *Bot) {
+	gm, err := b.GameStorage.RetrieveGame()
 
 	if err != nil {
 		return
 	}
-
-	gm.Lock()
-	defer gm.Unlock()
-	link, _ := s.LinkRenderer.CreateLink(gm)
-
-	boardAttachment := slack.Attachment{
-		ImageURL: link.String(),
-		Color:    colorToHex[gm.Turn()],
+	snapshot := gm.Snapshot()
+
+	if m.text {
+		text := fmt.Sprintf("```\n%s\n```", rendering.TextBoard(snapshot))
+		if m.ThreadTimestamp() != "" {
+			b.Chat.PostThreadReply(m.ChannelID(), m.ThreadTimestamp(), text)
+			return
+		}
+		b.Chat.PostText(m.ChannelID(), text)
+		return
 	}
 
-	s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText("Here is the current state of the game", false), slack.MsgOptionAttachments(boardAttachment))
+	// show the moves the channel is leaning towards so far
+	options := []rendering.Option{rendering.WithVotes(snapshot.Votes)}
+	if m.flip {
+		options = append(options, rendering.WithPerspective(snapshot.HumanColor.Other()))
+	}
+	b.postBoard(b.GameChannel, "Here is the current state of the game", snapshot, options...)
 }
 
 // HelpMsg represents a message about the help command
 type HelpMsg struct {
 	player string
-	raw    *slackevents.MessageEvent
+	raw    chat.Message
 }
 
 func (m HelpMsg) ChannelID() string {
-	return m.raw.Channel
+	return m.raw.ChannelID
 }
 
 func (m HelpMsg) Timestamp() string {
-	return m.raw.TimeStamp
+	return m.raw.Timestamp
 }
 
 func (m HelpMsg) ThreadTimestamp() string {
-	return m.raw.ThreadTimeStamp
+	return m.raw.ThreadID
 }
 
-func (m HelpMsg) Raw() *slackevents.MessageEvent {
+func (m HelpMsg) Raw() chat.Message {
 	return m.raw
 }
 
-func ParseHelpMsg(m *slackevents.MessageEvent) (*HelpMsg, bool) {
+func ParseHelpMsg(m chat.Message) (*HelpMsg, bool) {
 	// cannot be in a thread
-	if m.ThreadTimeStamp != "" {
+	if m.ThreadID != "" {
 		return nil, false
 	}
 
 	// it is in a DM
-	if strings.HasPrefix(m.Channel, "D") {
+	if m.Direct {
 		return nil, false
 	}
 
 	if m.Text == "!help" {
-		return &HelpMsg{raw: m, player: m.User}, true
+		return &HelpMsg{raw: m, player: m.UserID}, true
+	}
+
+	return nil, false
+}
+
+func (m HelpMsg) Handle(b *Bot) {
+	helpText := "K: King, Q: Queen, R: Rook, B: Bishop, N: Knight, Pawn: no shorthand needed.\nTo vote on a move type '!move [notation]'. You don't have to specify which square a piece is on as long as it is not a capture or *two pieces can move to the same square*.\n*'!move e4'* will move the pawn to e4. *'!move Nc6'* will move the Knight to c6. *To castle* use !move O-O or O-O-O\nYou can *capture* other pieces like *!move dxe4* which indicates the d pawn will capture the piece on e4. Nxc3 would mean that you want your knight to capture on c3.\nFinally, you can *promote* with the equal sign *!move e8=Q* will move your pawn to e8 and promote to a queen.\n*'!theme'* lists the board themes and *'!theme green'* changes the board theme of this channel. *'!replay'* shows an animation of the game so far. *'!votes'* shows which moves have been voted on this turn. *'!board flip'* shows the board from the bot's side and *'!board text'* shows it as text, which also works in threads and DMs.\n*'!stats'* or *'!stats @someone'* shows how many games they played and won, *'!leaderboard week'* (or month, all) shows the best players."
+	b.Chat.PostText(b.GameChannel, helpText)
+}
+
+// VotesMsg represents a message to ask which moves have been voted so far
+type VotesMsg struct {
+	player string
+	raw    chat.Message
+}
+
+func (m VotesMsg) ChannelID() string {
+	return m.raw.ChannelID
+}
+
+func (m VotesMsg) Timestamp() string {
+	return m.raw.Timestamp
+}
+
+func (m VotesMsg) ThreadTimestamp() string {
+	return m.raw.ThreadID
+}
+
+func (m VotesMsg) Raw() chat.Message {
+	return m.raw
+}
+
+func ParseVotesMsg(m chat.Message) (*VotesMsg, bool) {
+	// cannot be in a thread
+	if m.ThreadID != "" {
+		return nil, false
+	}
+
+	// it is in a DM
+	if m.Direct {
+		return nil, false
+	}
+
+	if m.Text == "!votes" {
+		return &VotesMsg{raw: m, player: m.UserID}, true
+	}
+
+	return nil, false
+}
+
+func (m VotesMsg) Handle(b *Bot) {
+	gm, err := b.GameStorage.RetrieveGame()
+
+	if err != nil {
+		b.Chat.PostText(m.ChannelID(), "There isn't an active game at the moment :( You can use the *!start* command to start a new game :chess_pawn: ")
+		return
+	}
+
+	snapshot := gm.Snapshot()
+	if len(snapshot.Votes) == 0 {
+		b.Chat.PostText(m.ChannelID(), "Nobody has voted yet. Vote on a move with *!move [notation]*")
+		return
+	}
+
+	b.postBoard(b.GameChannel, voteSummary(snapshot.Votes), snapshot, rendering.WithVotes(snapshot.Votes))
+}
+
+// ReplayMsg represents a message to ask for an animated replay of the current game
+type ReplayMsg struct {
+	player string
+	raw    chat.Message
+}
+
+func (m ReplayMsg) ChannelID() string {
+	return m.raw.ChannelID
+}
+
+func (m ReplayMsg) Timestamp() string {
+	return m.raw.Timestamp
+}
+
+func (m ReplayMsg) ThreadTimestamp() string {
+	return m.raw.ThreadID
+}
+
+func (m ReplayMsg) Raw() chat.Message {
+	return m.raw
+}
+
+func ParseReplayMsg(m chat.Message) (*ReplayMsg, bool) {
+	// cannot be in a thread
+	if m.ThreadID != "" {
+		return nil, false
+	}
+
+	// it is in a DM
+	if m.Direct {
+		return nil, false
+	}
+
+	if m.Text == "!replay" {
+		return &ReplayMsg{raw: m, player: m.UserID}, true
 	}
 
 	return nil, false
 }
 
-func (m HelpMsg) Handle(s *SlackHandler) {
-	helpText := "K: King, Q: Queen, R: Rook, B: Bishop, N: Knight, Pawn: no shorthand needed.\nTo vote on a move type '!move [notation]'. You don't have to specify which square a piece is on as long as it is not a capture or *two pieces can move to the same square*.\n*'!move e4'* will move the pawn to e4. *'!move Nc6'* will move the Knight to c6. *To castle* use !move O-O or O-O-O\nYou can *capture* other pieces like *!move dxe4* which indicates the d pawn will capture the piece on e4. Nxc3 would mean that you want your knight to capture on c3.\nFinally, you can *promote* with the equal sign *!move e8=Q* will move your pawn to e8 and promote to a queen."
-	s.SlackClient.PostMessage(s.GameChannel, slack.MsgOptionText(helpText, false))
+func (m ReplayMsg) Handle(b *Bot) {
+	gm, err := b.GameStorage.RetrieveGame()
+
+	if err != nil {
+		b.Chat.PostText(m.ChannelID(), "There isn't an active game at the moment :( You can use the *!start* command to start a new game :chess_pawn: ")
+		return
+	}
+
+	snapshot := gm.Snapshot()
+	if len(snapshot.Moves) == 0 {
+		b.Chat.PostText(m.ChannelID(), "No moves have been played yet. Vote on a move with *!move [notation]*")
+		return
+	}
+
+	b.postReplay(b.GameChannel, "Here is how the game went so far :film_projector:", snapshot)
+}
+
+// ThemeMsg represents a message to show or change the board theme of a channel
+type ThemeMsg struct {
+	player string
+	theme  string
+	raw    chat.Message
+}
+
+func (m ThemeMsg) ChannelID() string {
+	return m.raw.ChannelID
+}
+
+func (m ThemeMsg) Timestamp() string {
+	return m.raw.Timestamp
+}
+
+func (m ThemeMsg) ThreadTimestamp() string {
+	return m.raw.ThreadID
+}
+
+func (m ThemeMsg) Raw() chat.Message {
+	return m.raw
+}
+
+func ParseThemeMsg(m chat.Message) (*ThemeMsg, bool) {
+	// cannot be in a thread
+	if m.ThreadID != "" {
+		return nil, false
+	}
+
+	// it is in a DM
+	if m.Direct {
+		return nil, false
+	}
+
+	if m.Text == "!theme" {
+		return &ThemeMsg{raw: m, player: m.UserID}, true
+	}
+
+	regex := regexp.MustCompile("^!theme (.*)$")
+	matches := regex.FindStringSubmatch(m.Text)
+	if matches == nil {
+		return nil, false
+	}
+
+	return &ThemeMsg{raw: m, player: m.UserID, theme: strings.ToLower(strings.TrimSpace(matches[1]))}, true
+}
+
+func (m ThemeMsg) Handle(b *Bot) {
+	themes := strings.Join(rendering.ThemeNames(), ", ")
+
+	if m.theme == "" {
+		text := fmt.Sprintf("The board theme of this channel is *%s*. Available themes: %s", b.Settings.Theme(m.ChannelID()), themes)
+		b.Chat.PostText(m.ChannelID(), text)
+		return
+	}
+
+	if _, ok := rendering.LookupTheme(m.theme); !ok {
+		text := fmt.Sprintf("I don't know the theme *%s*. Available themes: %s", m.theme, themes)
+		b.Chat.PostText(m.ChannelID(), text)
+		return
+	}
+
+	if b.Settings == nil {
+		text := fmt.Sprintf("Board themes can't be changed here, boards use the *%s* theme", rendering.DefaultTheme)
+		b.Chat.PostText(m.ChannelID(), text)
+		return
+	}
+
+	log.Println(m.player, "changed the board theme to", m.theme)
+	b.Settings.SetTheme(m.ChannelID(), m.theme)
+	text := fmt.Sprintf("Boards in this channel now use the *%s* theme", m.theme)
+	b.Chat.PostText(m.ChannelID(), text)
 }
 
 // This parses messages to either a msg to start the game or to play a move
-func parseMessage(msg *slackevents.MessageEvent) Msg {
+func parseMessage(msg chat.Message) Msg {
 	var parsed Msg
 	var ok bool
 
@@ -314,6 +522,31 @@ func parseMessage(msg *slackevents.MessageEvent) Msg {
 		return parsed
 	}
 
+	parsed, ok = ParseThemeMsg(msg)
+	if ok {
+		return parsed
+	}
+
+	parsed, ok = ParseReplayMsg(msg)
+	if ok {
+		return parsed
+	}
+
+	parsed, ok = ParseVotesMsg(msg)
+	if ok {
+		return parsed
+	}
+
+	parsed, ok = ParseStatsMsg(msg)
+	if ok {
+		return parsed
+	}
+
+	parsed, ok = ParseLeaderboardMsg(msg)
+	if ok {
+		return parsed
+	}
+
 	return nil
 
 }
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat"
+	"github.com/dyslexicat/collab-chess/chat/chattest"
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+const testChannel = "C1"
+
+// newTestBot returns a bot on a fake chat platform with a game stored where the channel plays White.
+// No game loop runs, the tests only look at how messages are answered
+func newTestBot(t *testing.T) (*chattest.Fake, *game.Game, Bot) {
+	t.Helper()
+	fake := chattest.NewFake(nil)
+	bot := Bot{
+		Chat:         fake,
+		GameStorage:  game.NewMemoryStore(),
+		LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key"),
+		GameChannel:  testChannel,
+		Settings:     NewChannelSettings(),
+	}
+	fake.Handler = bot
+
+	gm := game.NewGame("test", "white", game.Player{ID: "chessbot"}, game.Player{ID: "U1"})
+	if err := bot.GameStorage.StoreGame(gm); err != nil {
+		t.Fatal(err)
+	}
+	return fake, gm, bot
+}
+
+// send delivers a message written in the game channel
+func send(fake *chattest.Fake, userID string, text string) {
+	fake.Send(chat.Message{ChannelID: testChannel, UserID: userID, Text: text})
+}
+
+// onlyPost returns the single post made since the last reset
+func onlyPost(t *testing.T, fake *chattest.Fake) chattest.Post {
+	t.Helper()
+	posts := fake.Posts()
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1: %+v", len(posts), posts)
+	}
+	fake.Reset()
+	return posts[0]
+}
+
+func TestBoardTextRepliesInThreads(t *testing.T) {
+	fake, _, _ := newTestBot(t)
+
+	fake.Send(chat.Message{ChannelID: testChannel, ThreadID: "1.000", UserID: "U1", Text: "!board text"})
+	post := onlyPost(t, fake)
+	if post.ThreadID != "1.000" {
+		t.Fatalf("got thread %q, want the reply in thread 1.000", post.ThreadID)
+	}
+	if !strings.HasPrefix(post.Text, "```\n") || !strings.Contains(post.Text, "White to move") {
+		t.Fatalf("got %q, want a text board", post.Text)
+	}
+
+	// the image board is only shown in the channel itself
+	fake.Send(chat.Message{ChannelID: testChannel, ThreadID: "1.000", UserID: "U1", Text: "!board"})
+	if posts := fake.Posts(); len(posts) != 0 {
+		t.Fatalf("got %+v, want !board in a thread to be ignored", posts)
+	}
+}
+
+func TestVotesListsTheVotesOfTheTurn(t *testing.T) {
+	fake, _, _ := newTestBot(t)
+
+	send(fake, "U1", "!votes")
+	if post := onlyPost(t, fake); !strings.Contains(post.Text, "Nobody has voted yet") {
+		t.Fatalf("got %q", post.Text)
+	}
+
+	send(fake, "U1", "!move e4")
+	send(fake, "U2", "!move e4")
+	send(fake, "U3", "!move d4")
+	if posts := fake.Posts(); len(posts) != 0 {
+		t.Fatalf("got %+v, want valid votes to be taken silently", posts)
+	}
+
+	send(fake, "U2", "!votes")
+	post := onlyPost(t, fake)
+	if want := "Votes so far: *e4* (2 votes), *d4* (1 vote)"; post.Text != want {
+		t.Fatalf("got %q, want %q", post.Text, want)
+	}
+	if post.Image == nil || !strings.Contains(post.Image.URL, "arrows=") || post.Image.Alt == "" {
+		t.Fatalf("got image %+v, want a board link with vote arrows and a text board", post.Image)
+	}
+
+	fake.Send(chat.Message{ChannelID: "D1", UserID: "U2", Text: "!votes", Direct: true})
+	if posts := fake.Posts(); len(posts) != 0 {
+		t.Fatalf("got %+v, want !votes in a DM to be ignored", posts)
+	}
+}
+
+func TestThemeShowsAndChangesTheChannelTheme(t *testing.T) {
+	fake, _, bot := newTestBot(t)
+
+	send(fake, "U1", "!theme")
+	if post := onlyPost(t, fake); !strings.Contains(post.Text, "is *brown*") {
+		t.Fatalf("got %q", post.Text)
+	}
+
+	send(fake, "U1", "!theme Green")
+	if post := onlyPost(t, fake); post.Text != "Boards in this channel now use the *green* theme" {
+		t.Fatalf("got %q", post.Text)
+	}
+	if theme := bot.Settings.Theme(testChannel); theme != "green" {
+		t.Fatalf("got theme %q, want green", theme)
+	}
+
+	send(fake, "U1", "!theme neon")
+	if post := onlyPost(t, fake); !strings.Contains(post.Text, "I don't know the theme *neon*") {
+		t.Fatalf("got %q", post.Text)
+	}
+	if theme := bot.Settings.Theme(testChannel); theme != "green" {
+		t.Fatalf("got theme %q, want an unknown theme to leave green alone", theme)
+	}
+}
+
+func TestThemeWithoutSettingsKeepsTheDefault(t *testing.T) {
+	fake, _, bot := newTestBot(t)
+	bot.Settings = nil
+	fake.Handler = bot
+
+	send(fake, "U1", "!theme green")
+	if post := onlyPost(t, fake); post.Text != "Board themes can't be changed here, boards use the *brown* theme" {
+		t.Fatalf("got %q", post.Text)
+	}
+	send(fake, "U1", "!theme")
+	if post := onlyPost(t, fake); !strings.Contains(post.Text, "is *brown*") {
+		t.Fatalf("got %q", post.Text)
+	}
+}
+
+func TestInvalidMovesAreOnlyShownToTheVoter(t *testing.T) {
+	fake, gm, _ := newTestBot(t)
+
+	send(fake, "U2", "!move e5")
+	post := onlyPost(t, fake)
+	if post.UserID != "U2" {
+		t.Fatalf("got a post for %q, want an ephemeral message for U2", post.UserID)
+	}
+	if !strings.HasPrefix(post.Text, "*e5* is not a valid move right now") {
+		t.Fatalf("got %q", post.Text)
+	}
+	if votes := gm.Votes(); len(votes) != 0 {
+		t.Fatalf("got votes %v, want the invalid move not to count", votes)
+	}
+}
+
+// firstMoveEngine plays the first legal move so games don't need Stockfish
+type firstMoveEngine struct{}
+
+func (firstMoveEngine) Move(position *chess.Position) (*chess.Move, game.Evaluation, error) {
+	return position.ValidMoves()[0], game.Evaluation{}, nil
+}
+
+func (firstMoveEngine) Close() error {
+	return nil
+}
+
+func TestStartedGamesUseTheVoteDurationOfTheBot(t *testing.T) {
+	fake := chattest.NewFake(nil)
+	bot := Bot{
+		Chat:         fake,
+		GameStorage:  game.NewMemoryStore(),
+		LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key"),
+		GameChannel:  testChannel,
+		Settings:     NewChannelSettings(),
+		NewEngine: func() (Engine, error) {
+			return firstMoveEngine{}, nil
+		},
+		VoteDuration: 5 * time.Minute,
+	}
+	fake.Handler = bot
+	// the game loop stops once the game is gone
+	defer bot.GameStorage.RemoveGame()
+
+	send(fake, "U1", "!start white")
+	gm, err := bot.GameStorage.RetrieveGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := gm.VoteDuration(); d != 5*time.Minute {
+		t.Fatalf("got a vote duration of %v, want 5m", d)
+	}
+
+	if err := gm.Vote("U1", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := gm.Snapshot()
+	if got, want := snapshot.VoteDeadline, snapshot.FirstVoteTime.Add(5*time.Minute); !got.Equal(want) {
+		t.Fatalf("got the deadline %v, want %v", got, want)
+	}
+	if deadline, _ := gm.VoteDeadline(); !deadline.Equal(snapshot.VoteDeadline) {
+		t.Fatalf("got the deadline %v from the game and %v from the snapshot", deadline, snapshot.VoteDeadline)
+	}
+}
+
+func TestOnlyOneOfTwoSimultaneousStartsStartsAGame(t *testing.T) {
+	fake := chattest.NewFake(nil)
+	bot := Bot{
+		Chat:         fake,
+		GameStorage:  game.NewMemoryStore(),
+		LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key"),
+		GameChannel:  testChannel,
+		Settings:     NewChannelSettings(),
+		NewEngine: func() (Engine, error) {
+			return firstMoveEngine{}, nil
+		},
+	}
+	fake.Handler = bot
+	defer bot.GameStorage.RemoveGame()
+
+	var wg sync.WaitGroup
+	for _, user := range []string{"U1", "U2"} {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			send(fake, user, "!start white")
+		}(user)
+	}
+	wg.Wait()
+
+	var started, refused int
+	for _, post := range fake.Posts() {
+		switch {
+		case strings.HasPrefix(post.Text, "Hackalackers are playing"):
+			started++
+		case strings.HasPrefix(post.Text, "There is already a game in place"):
+			refused++
+		}
+	}
+	if started != 1 || refused != 1 {
+		t.Fatalf("got %d games started and %d refused, want one of each: %+v", started, refused, fake.Posts())
+	}
+}
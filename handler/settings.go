@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/dyslexicat/collab-chess/rendering"
+)
+
+// ChannelSettings holds the preferences of each channel
+type ChannelSettings struct {
+	themes map[string]string
+	sync.Mutex
+}
+
+// NewChannelSettings returns a ChannelSettings pointer
+func NewChannelSettings() *ChannelSettings {
+	return &ChannelSettings{themes: make(map[string]string)}
+}
+
+// Theme returns the board theme of a channel
+func (c *ChannelSettings) Theme(channel string) string {
+	if c == nil {
+		return rendering.DefaultTheme
+	}
+	c.Lock()
+	defer c.Unlock()
+	if theme, ok := c.themes[channel]; ok {
+		return theme
+	}
+	return rendering.DefaultTheme
+}
+
+// SetTheme changes the board theme of a channel, nil settings keep the default theme
+func (c *ChannelSettings) SetTheme(channel string, theme string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.themes[channel] = theme
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat"
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+// leaderboardPeriods maps the !leaderboard argument to how far back it looks, zero meaning all time
+var leaderboardPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// StatsMsg represents a message to ask for the statistics of a player
+type StatsMsg struct {
+	player string
+	// target is the player whose statistics are shown
+	target string
+	raw    chat.Message
+}
+
+func (m StatsMsg) ChannelID() string {
+	return m.raw.ChannelID
+}
+
+func (m StatsMsg) Timestamp() string {
+	return m.raw.Timestamp
+}
+
+func (m StatsMsg) ThreadTimestamp() string {
+	return m.raw.ThreadID
+}
+
+func (m StatsMsg) Raw() chat.Message {
+	return m.raw
+}
+
+func ParseStatsMsg(m chat.Message) (*StatsMsg, bool) {
+	// cannot be in a thread
+	if m.ThreadID != "" {
+		return nil, false
+	}
+
+	// it is in a DM
+	if m.Direct {
+		return nil, false
+	}
+
+	if m.Text == "!stats" {
+		return &StatsMsg{raw: m, player: m.UserID, target: m.UserID}, true
+	}
+
+	// mentions look like <@U123> or <@U123|name>, IRC nicks and the IDs of web and API voters
+	// like web:alice are given as they are
+	regex := regexp.MustCompile(`^!stats (?:<@([^>|\s]+)(?:\|[^>]*)?>|([^<>\s]+))$`)
+	matches := regex.FindStringSubmatch(m.Text)
+	if matches == nil {
+		return nil, false
+	}
+
+	target := matches[1]
+	if target == "" {
+		target = matches[2]
+	}
+	return &StatsMsg{raw: m, player: m.UserID, target: target}, true
+}
+
+func (m StatsMsg) Handle(b *Bot) {
+	records, err := b.GameStorage.Records()
+	if err != nil {
+		log.Println("could not load the game records:", err)
+		return
+	}
+
+	ps, ok := game.ComputeStats(records, time.Time{})[m.target]
+	if !ok {
+		text := fmt.Sprintf("<@%s> hasn't played a game yet. Type *!start* to start one :chess_pawn:", m.target)
+		b.Chat.PostText(m.ChannelID(), text)
+		return
+	}
+
+	abandoned := ""
+	if ps.Abandoned > 0 {
+		abandoned = fmt.Sprintf(", %d abandoned", ps.Abandoned)
+	}
+	text := fmt.Sprintf("*Stats for <@%s>*\n%s (%d won, %d drawn, %d lost against me%s)\n%s, %d of them became the played move\nWinning streak: %d (best %d)",
+		ps.PlayerID,
+		plural(ps.Games, "game"), ps.Wins, ps.Draws, ps.Losses, abandoned,
+		plural(ps.Votes, "vote"), ps.PlayedVotes,
+		ps.CurrentStreak, ps.BestStreak)
+	b.Chat.PostText(m.ChannelID(), text)
+}
+
+// LeaderboardMsg represents a message to ask for the best players of a period
+type LeaderboardMsg struct {
+	player string
+	period string
+	raw    chat.Message
+}
+
+func (m LeaderboardMsg) ChannelID() string {
+	return m.raw.ChannelID
+}
+
+func (m LeaderboardMsg) Timestamp() string {
+	return m.raw.Timestamp
+}
+
+func (m LeaderboardMsg) ThreadTimestamp() string {
+	return m.raw.ThreadID
+}
+
+func (m LeaderboardMsg) Raw() chat.Message {
+	return m.raw
+}
+
+func ParseLeaderboardMsg(m chat.Message) (*LeaderboardMsg, bool) {
+	// cannot be in a thread
+	if m.ThreadID != "" {
+		return nil, false
+	}
+
+	// it is in a DM
+	if m.Direct {
+		return nil, false
+	}
+
+	if m.Text == "!leaderboard" {
+		return &LeaderboardMsg{raw: m, player: m.UserID, period: "all"}, true
+	}
+
+	regex := regexp.MustCompile("^!leaderboard (week|month|all)$")
+	matches := regex.FindStringSubmatch(m.Text)
+	if matches == nil {
+		return nil, false
+	}
+
+	return &LeaderboardMsg{raw: m, player: m.UserID, period: matches[1]}, true
+}
+
+func (m LeaderboardMsg) Handle(b *Bot) {
+	records, err := b.GameStorage.Records()
+	if err != nil {
+		log.Println("could not load the game records:", err)
+		return
+	}
+
+	var since time.Time
+	if period := leaderboardPeriods[m.period]; period > 0 {
+		since = time.Now().Add(-period)
+	}
+
+	board := game.Leaderboard(game.ComputeStats(records, since))
+	if len(board) == 0 {
+		b.Chat.PostText(m.ChannelID(), "No games were finished in that period. Type *!start* to start one :chess_pawn:")
+		return
+	}
+
+	var text strings.Builder
+	title := map[string]string{"week": "this week", "month": "this month", "all": "of all time"}[m.period]
+	fmt.Fprintf(&text, ":trophy: *Leaderboard %s*\n", title)
+	for i, ps := range board {
+		if i == leaderboardSize {
+			break
+		}
+		fmt.Fprintf(&text, "%d. <@%s> %d W / %d D / %d L in %s, %d played %s\n",
+			i+1, ps.PlayerID, ps.Wins, ps.Draws, ps.Losses, plural(ps.Games, "game"), ps.PlayedVotes, pluralNoun(ps.PlayedVotes, "vote"))
+	}
+	b.Chat.PostText(m.ChannelID(), text.String())
+}
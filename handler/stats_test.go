@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/chat"
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+func TestParseStatsMsg(t *testing.T) {
+	tests := []struct {
+		text string
+		// target is the player whose stats are asked for, empty when the message isn't !stats
+		target string
+	}{
+		{"!stats", "U1"},
+		{"!stats <@U2>", "U2"},
+		{"!stats <@U2|bob>", "U2"},
+		{"!stats 123456789", "123456789"},
+		{"!stats alice", "alice"},
+		{"!stats api:bot-7", "api:bot-7"},
+		{"!stats web:Alice", "web:Alice"},
+		{"!stats alice bob", ""},
+		{"!stats <@U2", ""},
+		{"!stats ", ""},
+		{"!statsalice", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			msg, ok := ParseStatsMsg(chat.Message{ChannelID: testChannel, UserID: "U1", Text: tt.text})
+			if tt.target == "" {
+				if ok {
+					t.Fatalf("got %+v, want the message not to be !stats", msg)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("got no !stats message")
+			}
+			if msg.target != tt.target {
+				t.Fatalf("got %q, want %q", msg.target, tt.target)
+			}
+		})
+	}
+
+	if _, ok := ParseStatsMsg(chat.Message{UserID: "U1", Text: "!stats", Direct: true}); ok {
+		t.Fatal("got !stats in a direct message, want it ignored")
+	}
+}
+
+func TestStatsShowsThePlayer(t *testing.T) {
+	fake, _, bot := newTestBot(t)
+	now := time.Now()
+	for _, record := range []game.Record{
+		{EndedAt: now.Add(-2 * time.Hour), Result: game.Win, Participants: []string{"U1", "alice"}},
+		{EndedAt: now.Add(-time.Hour), Result: game.Abandoned, Participants: []string{"alice"}},
+	} {
+		if err := bot.GameStorage.SaveRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"!stats", "*Stats for <@U1>*\n1 game (1 won, 0 drawn, 0 lost against me)\n0 votes, 0 of them became the played move\nWinning streak: 1 (best 1)"},
+		{"!stats alice", "*Stats for <@alice>*\n2 games (1 won, 0 drawn, 0 lost against me, 1 abandoned)\n0 votes, 0 of them became the played move\nWinning streak: 0 (best 1)"},
+		{"!stats <@U9>", "<@U9> hasn't played a game yet. Type *!start* to start one :chess_pawn:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			send(fake, "U1", tt.text)
+			if post := onlyPost(t, fake); post.Text != tt.want {
+				t.Fatalf("got %q, want %q", post.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardPeriods(t *testing.T) {
+	fake, _, bot := newTestBot(t)
+	now := time.Now()
+	for _, record := range []game.Record{
+		{EndedAt: now.Add(-2 * 24 * time.Hour), Result: game.Win, Participants: []string{"U3"}},
+		{EndedAt: now.Add(-10 * 24 * time.Hour), Result: game.Win, Participants: []string{"U2"}},
+		{EndedAt: now.Add(-11 * 24 * time.Hour), Result: game.Loss, Participants: []string{"U2"}},
+		{EndedAt: now.Add(-60 * 24 * time.Hour), Result: game.Draw, Participants: []string{"U1"}},
+	} {
+		if err := bot.GameStorage.SaveRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"!leaderboard week", []string{
+			":trophy: *Leaderboard this week*",
+			"1. <@U3> 1 W / 0 D / 0 L in 1 game, 0 played votes",
+		}},
+		{"!leaderboard month", []string{
+			":trophy: *Leaderboard this month*",
+			"1. <@U2> 1 W / 0 D / 1 L in 2 games, 0 played votes",
+			"2. <@U3> 1 W / 0 D / 0 L in 1 game, 0 played votes",
+		}},
+		{"!leaderboard", []string{
+			":trophy: *Leaderboard of all time*",
+			"1. <@U2> 1 W / 0 D / 1 L in 2 games, 0 played votes",
+			"2. <@U3> 1 W / 0 D / 0 L in 1 game, 0 played votes",
+			"3. <@U1> 0 W / 1 D / 0 L in 1 game, 0 played votes",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			send(fake, "U1", tt.text)
+			if post, want := onlyPost(t, fake), strings.Join(tt.want, "\n")+"\n"; post.Text != want {
+				t.Fatalf("got %q, want %q", post.Text, want)
+			}
+		})
+	}
+
+	// records that are too old leave the period empty
+	bot.GameStorage = game.NewMemoryStore()
+	fake.Handler = bot
+	send(fake, "U1", "!leaderboard week")
+	if post := onlyPost(t, fake); !strings.HasPrefix(post.Text, "No games were finished in that period") {
+		t.Fatalf("got %q", post.Text)
+	}
+}
+
+func TestInactiveGamesAreRecorded(t *testing.T) {
+	fake, gm, bot := newTestBot(t)
+	if err := gm.Vote("U2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gm.MoveTopVote(); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.stopInactiveGame(gm)
+	if _, err := bot.GameStorage.RetrieveGame(); err == nil {
+		t.Fatal("got a game, want the inactive game removed")
+	}
+	if post := onlyPost(t, fake); !strings.HasPrefix(post.Text, "Nobody made a move in a while") {
+		t.Fatalf("got %q", post.Text)
+	}
+
+	records, err := bot.GameStorage.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Result != game.Abandoned {
+		t.Fatalf("got %+v, want the game recorded as abandoned", records)
+	}
+	send(fake, "U2", "!stats")
+	if post := onlyPost(t, fake); !strings.Contains(post.Text, "1 vote, 1 of them became the played move") {
+		t.Fatalf("got %q, want the votes of the abandoned game counted", post.Text)
+	}
+}
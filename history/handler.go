@@ -0,0 +1,267 @@
+package history
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+// gamesPerPage is how many finished games are listed on a single page
+const gamesPerPage = 50
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"result": resultText,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// Handler serves the archive of finished games under /games
+type Handler struct {
+	GameStorage  game.ChessStorage
+	LinkRenderer rendering.RenderLink
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/games"), "/")
+	if id == "" {
+		h.serveList(w, r)
+		return
+	}
+	if strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	h.serveGame(w, r, id)
+}
+
+// listPage is the data of the page listing the finished games
+type listPage struct {
+	Games    []game.Record
+	Page     int
+	PrevPage int
+	NextPage int
+}
+
+func (h Handler) serveList(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			http.Error(w, fmt.Sprintf("invalid page %q", p), http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+
+	records, err := h.GameStorage.Records()
+	if err != nil {
+		log.Println("could not load the game records:", err)
+		http.Error(w, "could not load the games", http.StatusInternalServerError)
+		return
+	}
+	// latest games first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].EndedAt.After(records[j].EndedAt)
+	})
+
+	render(w, "games.html", paginate(records, page))
+}
+
+// paginate returns the games of the page, pages past the last game are empty
+func paginate(records []game.Record, page int) listPage {
+	data := listPage{Page: page}
+	start := (page - 1) * gamesPerPage
+	if start < len(records) {
+		end := start + gamesPerPage
+		if end > len(records) {
+			end = len(records)
+		}
+		data.Games = records[start:end]
+		if end < len(records) {
+			data.NextPage = page + 1
+		}
+	}
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	return data
+}
+
+// moveRow is a full move of the move list, Black is empty if the game ended on White's move
+type moveRow struct {
+	Number int
+	White  moveCell
+	Black  *moveCell
+}
+
+// moveCell is a single move of the move list linking to the position after it
+type moveCell struct {
+	SAN     string
+	Ply     int
+	Current bool
+}
+
+// voteCount is a move and everybody who voted for it
+type voteCount struct {
+	game.VoteCount
+	Played bool
+}
+
+// gamePage is the data of the page of a single finished game
+type gamePage struct {
+	Record game.Record
+	// Ply is the number of moves played in the shown position
+	Ply      int
+	LastPly  int
+	PrevPly  int
+	NextPly  int
+	BoardURL string
+	LastMove string
+	Moves    []moveRow
+	Votes    []voteCount
+}
+
+func (h Handler) serveGame(w http.ResponseWriter, r *http.Request, id string) {
+	record, err := h.GameStorage.Record(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	san, err := record.SANMoves()
+	if err != nil {
+		log.Println("could not replay game", record.ID, err)
+		http.Error(w, "could not replay the game", http.StatusInternalServerError)
+		return
+	}
+	positions, err := record.Positions()
+	if err != nil {
+		log.Println("could not replay game", record.ID, err)
+		http.Error(w, "could not replay the game", http.StatusInternalServerError)
+		return
+	}
+
+	// the final position is shown unless another one was asked for
+	ply := len(san)
+	if p := r.URL.Query().Get("ply"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || n > len(san) {
+			http.Error(w, fmt.Sprintf("ply must be between 0 and %d", len(san)), http.StatusBadRequest)
+			return
+		}
+		ply = n
+	}
+
+	links, err := h.LinkRenderer.CreateRecordLinks(record)
+	if err != nil {
+		log.Println("could not create the board links of game", record.ID, err)
+		http.Error(w, "could not replay the game", http.StatusInternalServerError)
+		return
+	}
+
+	data := gamePage{
+		Record:   record,
+		Ply:      ply,
+		LastPly:  len(san),
+		PrevPly:  ply - 1,
+		NextPly:  ply + 1,
+		BoardURL: links[ply].String(),
+		Moves:    moveRows(san, ply),
+	}
+	if ply > 0 {
+		data.LastMove = san[ply-1]
+		data.Votes = votesOf(record.VoteHistory, positions[ply-1], ply-1)
+	}
+
+	render(w, "game.html", data)
+}
+
+// moveRows pairs the moves of White and Black for the move list
+func moveRows(san []string, current int) []moveRow {
+	rows := make([]moveRow, 0, (len(san)+1)/2)
+	for i := 0; i < len(san); i += 2 {
+		row := moveRow{
+			Number: i/2 + 1,
+			White:  moveCell{SAN: san[i], Ply: i + 1, Current: i+1 == current},
+		}
+		if i+1 < len(san) {
+			row.Black = &moveCell{SAN: san[i+1], Ply: i + 2, Current: i+2 == current}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// votesOf groups the votes of the round that chose the move at the given ply, most voted first.
+// The position is the one the votes were cast in. Bot moves have no votes
+func votesOf(rounds []game.VoteRound, position *chess.Position, ply int) []voteCount {
+	for _, round := range rounds {
+		if round.Ply != ply {
+			continue
+		}
+
+		// votes are compared as moves rather than text so Ngf3 is marked as played when Nf3 was
+		played, err := game.DecodeMove(position, round.Played)
+		tally := game.TallyVotes(round.Votes)
+		votes := make([]voteCount, len(tally))
+		for i, vc := range tally {
+			votes[i] = voteCount{VoteCount: vc, Played: vc.Move == round.Played}
+			if err != nil {
+				continue
+			}
+			if move, err := game.DecodeMove(position, vc.Move); err == nil {
+				votes[i].Played = game.SameMove(move, played)
+			}
+		}
+		return votes
+	}
+	return nil
+}
+
+// resultText describes the result of a game from the human players' side
+func resultText(result game.Result) string {
+	switch result {
+	case game.Win:
+		return "Won"
+	case game.Draw:
+		return "Drawn"
+	case game.Loss:
+		return "Lost"
+	case game.Abandoned:
+		return "Abandoned"
+	}
+	return "Unknown"
+}
+
+// render executes the named template, the page is only written once it rendered without errors
+func render(w http.ResponseWriter, name string, data interface{}) {
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, name, data); err != nil {
+		log.Println("could not render", name, err)
+		http.Error(w, "could not render the page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, b.String())
+}
@@ -0,0 +1,241 @@
+package history
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+// newTestHandler returns a handler over a store with the records
+func newTestHandler(t *testing.T, records ...game.Record) Handler {
+	t.Helper()
+	store := game.NewMemoryStore()
+	for _, record := range records {
+		if err := store.SaveRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Handler{GameStorage: store, LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key")}
+}
+
+// serve requests the path from the handler
+func serve(h Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// numberedRecords returns n records, the first one ended last
+func numberedRecords(n int) []game.Record {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := make([]game.Record, n)
+	for i := range records {
+		records[i] = game.Record{ID: fmt.Sprint(i), EndedAt: start.Add(-time.Duration(i) * time.Minute), Result: game.Win}
+	}
+	return records
+}
+
+func TestPaginate(t *testing.T) {
+	records := numberedRecords(2*gamesPerPage + 10)
+
+	tests := []struct {
+		page      int
+		wantFirst string
+		wantGames int
+		wantPrev  int
+		wantNext  int
+	}{
+		{page: 1, wantFirst: "0", wantGames: gamesPerPage, wantNext: 2},
+		{page: 2, wantFirst: fmt.Sprint(gamesPerPage), wantGames: gamesPerPage, wantPrev: 1, wantNext: 3},
+		{page: 3, wantFirst: fmt.Sprint(2 * gamesPerPage), wantGames: 10, wantPrev: 2},
+		{page: 4, wantGames: 0, wantPrev: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("page ", tt.page), func(t *testing.T) {
+			data := paginate(records, tt.page)
+			if len(data.Games) != tt.wantGames {
+				t.Fatalf("got %d games, want %d", len(data.Games), tt.wantGames)
+			}
+			if tt.wantGames > 0 && data.Games[0].ID != tt.wantFirst {
+				t.Fatalf("got game %s first, want %s", data.Games[0].ID, tt.wantFirst)
+			}
+			if data.PrevPage != tt.wantPrev || data.NextPage != tt.wantNext {
+				t.Fatalf("got the pages %d and %d around, want %d and %d", data.PrevPage, data.NextPage, tt.wantPrev, tt.wantNext)
+			}
+		})
+	}
+
+	// a full last page has no next page
+	if data := paginate(numberedRecords(gamesPerPage), 1); data.NextPage != 0 {
+		t.Fatalf("got the next page %d, want none", data.NextPage)
+	}
+}
+
+func TestServeList(t *testing.T) {
+	records := numberedRecords(gamesPerPage + 1)
+	// stores keep records in the order they were saved, the list shows the latest first
+	records[0], records[len(records)-1] = records[len(records)-1], records[0]
+	h := newTestHandler(t, records...)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   []string
+	}{
+		{"/games", http.StatusOK, []string{`href="/games/0"`, `href="/games?page=2"`}},
+		{"/games?page=2", http.StatusOK, []string{fmt.Sprintf(`href="/games/%d"`, gamesPerPage), `href="/games?page=1"`}},
+		{"/games?page=3", http.StatusOK, []string{"No games were finished yet."}},
+		{"/games?page=0", http.StatusBadRequest, []string{`invalid page "0"`}},
+		{"/games?page=-1", http.StatusBadRequest, nil},
+		{"/games?page=two", http.StatusBadRequest, nil},
+		{"/games/1/moves", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(h, tt.path)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Fatalf("got %s, want it to contain %s", w.Body.String(), want)
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/games", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("got status %d with Allow %q, want 405 with GET, HEAD", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestServeGamePly(t *testing.T) {
+	h := newTestHandler(t, game.Record{
+		ID:     "g1",
+		Result: game.Loss,
+		Moves:  []string{"f2f3", "e7e5", "g2g4", "d8h4"},
+		VoteHistory: []game.VoteRound{
+			{Ply: 0, Votes: map[string]string{"U1": "f3", "U2": "e4"}, Played: "f3"},
+			{Ply: 2, Votes: map[string]string{"U1": "g4"}, Played: "g4"},
+		},
+	}, game.Record{ID: "broken", Moves: []string{"e2e5"}})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   []string
+	}{
+		{"/games/g1", http.StatusOK, []string{"Move 4: Qh4#", "Played by the bot."}},
+		{"/games/g1?ply=0", http.StatusOK, []string{`alt="Board after 0 moves"`}},
+		{"/games/g1?ply=1", http.StatusOK, []string{"Move 1: f3", `<tr class="played"><td>f3</td><td>U1</td></tr>`, "<tr><td>e4</td><td>U2</td></tr>"}},
+		{"/games/g1?ply=4", http.StatusOK, []string{"Move 4: Qh4#"}},
+		{"/games/g1?ply=5", http.StatusBadRequest, []string{"ply must be between 0 and 4"}},
+		{"/games/g1?ply=-1", http.StatusBadRequest, []string{"ply must be between 0 and 4"}},
+		{"/games/g1?ply=last", http.StatusBadRequest, []string{"ply must be between 0 and 4"}},
+		{"/games/missing", http.StatusNotFound, nil},
+		{"/games/broken", http.StatusInternalServerError, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(h, tt.path)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Fatalf("got %s, want it to contain %s", w.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		san     []string
+		current int
+		want    string
+	}{
+		{"no moves", nil, 0, ""},
+		{"ends on White's move", []string{"e4", "e5", "Nf3"}, 3, "1. e4(1) e5(2) | 2. *Nf3(3)"},
+		{"ends on Black's move", []string{"e4", "e5"}, 1, "1. *e4(1) e5(2)"},
+		{"starting position", []string{"e4", "e5"}, 0, "1. e4(1) e5(2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []string
+			for _, row := range moveRows(tt.san, tt.current) {
+				text := fmt.Sprintf("%d. %s", row.Number, cellText(row.White))
+				if row.Black != nil {
+					text += " " + cellText(*row.Black)
+				}
+				rows = append(rows, text)
+			}
+			if got := strings.Join(rows, " | "); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// cellText writes a move cell as its move and ply, starred when it is the current move
+func cellText(cell moveCell) string {
+	text := fmt.Sprintf("%s(%d)", cell.SAN, cell.Ply)
+	if cell.Current {
+		text = "*" + text
+	}
+	return text
+}
+
+func TestVotesOf(t *testing.T) {
+	position := chess.StartingPosition()
+	rounds := []game.VoteRound{
+		{Ply: 0, Votes: map[string]string{"U1": "Nf3", "U2": "Ngf3", "U3": "g1f3", "U4": "e4", "U5": "e4"}, Played: "Nf3"},
+		{Ply: 2, Votes: map[string]string{"U1": "d4"}, Played: "d4"},
+	}
+
+	tests := []struct {
+		name   string
+		rounds []game.VoteRound
+		ply    int
+		want   string
+	}{
+		{"same move in other notations", rounds, 0, "e4:U4,U5 Nf3:U1* Ngf3:U2* g1f3:U3*"},
+		{"bot move", rounds, 1, ""},
+		{"unreadable votes are compared as text", []game.VoteRound{
+			{Ply: 0, Votes: map[string]string{"U1": "Zz9", "U2": "e4"}, Played: "Zz9"},
+		}, 0, "Zz9:U1* e4:U2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var votes []string
+			for _, vc := range votesOf(tt.rounds, position, tt.ply) {
+				text := vc.Move + ":" + strings.Join(vc.Players, ",")
+				if vc.Played {
+					text += "*"
+				}
+				votes = append(votes, text)
+			}
+			if got := strings.Join(votes, " "); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,11 +6,18 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/dyslexicat/collab-chess/api"
+	"github.com/dyslexicat/collab-chess/chat/discordchat"
+	"github.com/dyslexicat/collab-chess/chat/ircchat"
+	"github.com/dyslexicat/collab-chess/chat/slackchat"
 	"github.com/dyslexicat/collab-chess/game"
 	"github.com/dyslexicat/collab-chess/handler"
+	"github.com/dyslexicat/collab-chess/history"
 	"github.com/dyslexicat/collab-chess/rendering"
+	"github.com/dyslexicat/collab-chess/watch"
 
 	"github.com/joho/godotenv"
 )
@@ -28,31 +35,161 @@ func main() {
 	signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
 	hostname := os.Getenv("APP_HOSTNAME")
 	channelID := os.Getenv("CHANNEL_ID")
+	// without a public hostname Slack cannot fetch /board.png so we upload the images ourselves
+	uploadImages := os.Getenv("UPLOAD_IMAGES") == "true" || hostname == ""
+	showEvaluation := os.Getenv("SHOW_EVALUATION") == "true"
+
+	analysisMoveTime := 300 * time.Millisecond
+	if moveTime := os.Getenv("ANALYSIS_MOVE_TIME"); moveTime != "" {
+		analysisMoveTime, err = time.ParseDuration(moveTime)
+		if err != nil {
+			log.Fatal("ANALYSIS_MOVE_TIME must be a duration like 500ms, or 0 to turn the analysis off")
+		}
+	}
+
+	replayDelay := rendering.DefaultReplayDelay
+	if delay := os.Getenv("REPLAY_FRAME_DELAY"); delay != "" {
+		replayDelay, err = time.ParseDuration(delay)
+		// linked replays with a delay out of these bounds are turned away by /replay.gif
+		if err != nil || replayDelay < rendering.MinReplayDelay || replayDelay > rendering.MaxReplayDelay {
+			log.Fatalf("REPLAY_FRAME_DELAY must be a duration between %v and %v like 800ms or 1s", rendering.MinReplayDelay, rendering.MaxReplayDelay)
+		}
+	}
 
 	var gameStorage game.ChessStorage
 
-	memoryStore := game.NewMemoryStore()
-	gameStorage = memoryStore
+	// with RECORDS_FILE set finished games are kept on disk so statistics survive restarts
+	if recordsFile := os.Getenv("RECORDS_FILE"); recordsFile != "" {
+		fileStore, err := game.NewFileStore(recordsFile)
+		if err != nil {
+			log.Fatal("could not load the game records: ", err)
+		}
+		gameStorage = fileStore
+	} else {
+		memoryStore := game.NewMemoryStore()
+		gameStorage = memoryStore
+	}
 
 	renderLink := rendering.NewRenderLink(hostname, signingSecret)
 
-	sHandler := handler.SlackHandler{
-		SigningKey:   signingSecret,
-		BotToken:     slackAuthToken,
-		GameStorage:  gameStorage,
-		LinkRenderer: renderLink,
-		GameChannel:  channelID,
+	if err := rendering.PreloadAssets(); err != nil {
+		log.Fatal("could not load the piece images: ", err)
 	}
 
-	http.Handle("/slack/events", sHandler)
+	cacheSize, _ := strconv.Atoi(os.Getenv("RENDER_CACHE_SIZE"))
+	renderCache, err := rendering.NewImageCache(cacheSize, os.Getenv("RENDER_CACHE_DIR"))
+	if err != nil {
+		log.Fatal("could not create the render cache: ", err)
+	}
 
-	http.Handle("/board", rendering.BoardRenderHandler{
-		LinkRenderer: renderLink,
+	bot := handler.Bot{
+		Chat:             slackchat.New(slackAuthToken),
+		GameStorage:      gameStorage,
+		LinkRenderer:     renderLink,
+		GameChannel:      channelID,
+		UploadImages:     uploadImages,
+		Settings:         handler.NewChannelSettings(),
+		ReplayDelay:      replayDelay,
+		ShowEvaluation:   showEvaluation,
+		AnalysisMoveTime: analysisMoveTime,
+	}
+
+	http.Handle("/slack/events", slackchat.EventHandler{
+		SigningKey: signingSecret,
+		Handler:    bot,
 	})
-	http.Handle("/board.png", rendering.BoardRenderHandler{
+
+	boardHandler := rendering.BoardRenderHandler{
 		LinkRenderer: renderLink,
+		Cache:        renderCache,
+	}
+	http.Handle("/board", boardHandler)
+	http.Handle("/board.png", boardHandler)
+	http.Handle("/board.svg", boardHandler)
+	http.Handle("/replay.gif", rendering.ReplayRenderHandler{
+		LinkRenderer: renderLink,
+		Cache:        renderCache,
 	})
 
+	historyHandler := history.Handler{
+		GameStorage:  gameStorage,
+		LinkRenderer: renderLink,
+	}
+	http.Handle("/games", historyHandler)
+	http.Handle("/games/", historyHandler)
+
+	watchHandler := watch.Handler{
+		GameStorage:  gameStorage,
+		LinkRenderer: renderLink,
+	}
+	http.Handle("/watch", watchHandler)
+	http.Handle("/watch/", watchHandler)
+
+	// people without Slack can vote from /play with the WEB_VOTING_TOKEN code
+	playHandler := watch.PlayHandler{
+		Handler: watchHandler,
+		Token:   os.Getenv("WEB_VOTING_TOKEN"),
+	}
+	http.Handle("/play", playHandler)
+	http.Handle("/play/", playHandler)
+
+	// the JSON API is only served when there is a token to authenticate its clients with
+	if apiToken := os.Getenv("API_TOKEN"); apiToken != "" {
+		apiHandler := api.Handler{
+			GameStorage: gameStorage,
+			Token:       apiToken,
+		}
+		http.Handle("/api/v1/games", apiHandler)
+		http.Handle("/api/v1/games/", apiHandler)
+	}
+
+	// Discord plays a game of its own next to the Slack one
+	if discordToken := os.Getenv("DISCORD_BOT_TOKEN"); discordToken != "" {
+		discord, err := discordchat.New(discordToken)
+		if err != nil {
+			log.Fatal("could not create the Discord client: ", err)
+		}
+
+		discordBot := bot
+		discordBot.Chat = discord
+		discordBot.GameStorage = game.NewPlatformStore(gameStorage)
+		discordBot.GameChannel = os.Getenv("DISCORD_CHANNEL_ID")
+		if err := discord.Open(discordBot); err != nil {
+			log.Fatal("could not connect to Discord: ", err)
+		}
+		defer discord.Close()
+	}
+
+	// IRC plays a game of its own too, with text boards and links to the board images
+	if ircServer := os.Getenv("IRC_SERVER"); ircServer != "" {
+		nick := os.Getenv("IRC_NICK")
+		if nick == "" {
+			nick = "chessbot"
+		}
+		irc := ircchat.New(ircchat.Config{
+			Server:   ircServer,
+			TLS:      os.Getenv("IRC_TLS") == "true",
+			Nick:     nick,
+			Password: os.Getenv("IRC_PASSWORD"),
+			Channel:  os.Getenv("IRC_CHANNEL"),
+		})
+
+		ircBot := bot
+		ircBot.Chat = irc
+		ircBot.GameStorage = game.NewPlatformStore(gameStorage)
+		ircBot.GameChannel = os.Getenv("IRC_CHANNEL")
+		// IRC can't show uploaded images, only links to them
+		ircBot.UploadImages = false
+		if hostname == "" {
+			// the board links would be relative, the client leaves them out and posts the text boards alone
+			log.Println("APP_HOSTNAME is not set, IRC gets text boards without links to the board images")
+		}
+		if err := irc.Open(ircBot); err != nil {
+			log.Fatal("could not connect to IRC: ", err)
+		}
+		defer irc.Close()
+	}
+
 	fmt.Println("[INFO] Server listening")
 	http.ListenAndServe(":5000", nil)
 }
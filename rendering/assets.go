@@ -0,0 +1,82 @@
+package rendering
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// pieceAsset is a piece image loaded once and shared by every render
+type pieceAsset struct {
+	img image.Image
+	// encoded is the base64 PNG embedded into SVG boards
+	encoded string
+	// scaled holds the image resized for raster boards by size in pixels
+	scaled map[int]*image.RGBA
+	mu     sync.Mutex
+}
+
+var (
+	assetsMu sync.Mutex
+	assets   = make(map[string]*pieceAsset)
+)
+
+// loadAsset returns the piece image at path, reading it from disk the first time only
+func loadAsset(path string) (*pieceAsset, error) {
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+
+	if asset, ok := assets[path]; ok {
+		return asset, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	asset := &pieceAsset{
+		img:     img,
+		encoded: base64.StdEncoding.EncodeToString(data),
+		scaled:  make(map[int]*image.RGBA),
+	}
+	assets[path] = asset
+	return asset, nil
+}
+
+// resized returns the image scaled into a size x size square
+func (a *pieceAsset) resized(size int) *image.RGBA {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if img, ok := a.scaled[size]; ok {
+		return img
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(img, img.Bounds(), a.img, a.img.Bounds(), draw.Src, nil)
+	a.scaled[size] = img
+	return img
+}
+
+// PreloadAssets loads the piece images of every registered theme so the first renders don't hit the disk
+func PreloadAssets() error {
+	for _, name := range ThemeNames() {
+		theme, _ := LookupTheme(name)
+		for _, file := range pieceAssets {
+			if _, err := loadAsset(filepath.Join(theme.PieceDir, file)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package rendering
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// BoardParams holds everything needed to draw a single board position
+type BoardParams struct {
+	FEN      string
+	From     string
+	To       string
+	Check    string
+	Inverted bool
+	Theme    string
+	// Eval draws an evaluation bar next to the board when set
+	Eval *game.Evaluation
+	// Arrows are moves drawn as arrows on top of the board
+	Arrows []Arrow
+}
+
+// maxEvalPawns bounds evaluations read from links, engines never report more than this
+const maxEvalPawns = 1000
+
+// Option changes how a board is drawn
+type Option func(*BoardParams)
+
+// WithTheme draws the board with the named theme
+func WithTheme(name string) Option {
+	return func(p *BoardParams) {
+		p.Theme = name
+	}
+}
+
+// WithPerspective draws the board from the side of the given color
+func WithPerspective(c game.Color) Option {
+	return func(p *BoardParams) {
+		p.Inverted = c == game.Black
+	}
+}
+
+// WithEvaluation draws an evaluation bar next to the board
+func WithEvaluation(e game.Evaluation) Option {
+	return func(p *BoardParams) {
+		p.Eval = &e
+	}
+}
+
+// WithArrows draws an arrow for every move, for example candidate moves or an engine line
+func WithArrows(moves ...*chess.Move) Option {
+	return func(p *BoardParams) {
+		for _, move := range moves {
+			p.Arrows = append(p.Arrows, Arrow{Move: move.S1().String() + move.S2().String(), Weight: 1})
+		}
+	}
+}
+
+// WithVotes draws an arrow for every voted move (player ID -> move in algebraic notation)
+// with a thickness proportional to the number of votes it got
+func WithVotes(votes map[string]string) Option {
+	return func(p *BoardParams) {
+		fen, err := chess.FEN(p.FEN)
+		if err != nil {
+			return
+		}
+		position := chess.NewGame(fen).Position()
+
+		counts := make(map[string]int)
+		for _, san := range votes {
+			move, err := chess.AlgebraicNotation{}.Decode(position, san)
+			if err != nil {
+				continue
+			}
+			counts[move.S1().String()+move.S2().String()]++
+		}
+
+		arrows := make([]Arrow, 0, len(counts))
+		for move, count := range counts {
+			arrows = append(arrows, Arrow{Move: move, Weight: count})
+		}
+		// lightest arrows first so the popular moves are drawn on top
+		sort.Slice(arrows, func(i, j int) bool {
+			if arrows[i].Weight != arrows[j].Weight {
+				return arrows[i].Weight < arrows[j].Weight
+			}
+			return arrows[i].Move < arrows[j].Move
+		})
+		p.Arrows = append(p.Arrows, arrows...)
+	}
+}
+
+// paramsFromGame collects the board parameters for a snapshot of the game
+func paramsFromGame(snapshot game.Snapshot, options ...Option) BoardParams {
+	params := BoardParams{FEN: snapshot.FEN}
+	if lastMove := snapshot.LastMove; lastMove != nil {
+		params.From = lastMove.S1().String()
+		params.To = lastMove.S2().String()
+	}
+	if snapshot.CheckedKing != chess.NoSquare {
+		params.Check = snapshot.CheckedKing.String()
+	}
+	// boards face the human players so they don't flip back and forth every move
+	params.Inverted = snapshot.HumanColor == game.Black
+	for _, option := range options {
+		option(&params)
+	}
+	return params
+}
+
+// paramsFromQuery reads the board parameters back from a board URL query
+// and rejects anything that doesn't describe a drawable board
+func paramsFromQuery(query url.Values) (BoardParams, error) {
+	params := BoardParams{
+		FEN:   query.Get("fen"),
+		From:  query.Get("from"),
+		To:    query.Get("to"),
+		Check: query.Get("check"),
+	}
+
+	if params.FEN == "" {
+		return params, fmt.Errorf("fen is required")
+	}
+	if _, err := chess.FEN(params.FEN); err != nil {
+		return params, fmt.Errorf("invalid fen %q", params.FEN)
+	}
+
+	if (params.From == "") != (params.To == "") {
+		return params, fmt.Errorf("from and to must be given together")
+	}
+	if params.From != "" {
+		if err := validateSquare("from", params.From); err != nil {
+			return params, err
+		}
+		if err := validateSquare("to", params.To); err != nil {
+			return params, err
+		}
+		if params.From == params.To {
+			return params, fmt.Errorf("from and to must be different squares")
+		}
+	}
+	if params.Check != "" {
+		if err := validateSquare("check", params.Check); err != nil {
+			return params, err
+		}
+	}
+
+	err := parseDrawOptions(query, &params)
+	return params, err
+}
+
+// parseDrawOptions reads the options shared by boards and replays (orientation, theme and overlays)
+func parseDrawOptions(query url.Values, params *BoardParams) error {
+	switch inverted := query.Get("inverted"); inverted {
+	case "", "false":
+	case "true":
+		params.Inverted = true
+	default:
+		return fmt.Errorf("inverted must be true or false, not %q", inverted)
+	}
+
+	if theme := query.Get("theme"); theme != "" {
+		if _, ok := LookupTheme(theme); !ok {
+			return fmt.Errorf("unknown theme %q", theme)
+		}
+		params.Theme = theme
+	}
+
+	if eval := query.Get("eval"); eval != "" {
+		e, err := parseEvaluation(eval)
+		if err != nil {
+			return err
+		}
+		params.Eval = &e
+	}
+
+	if arrows := query.Get("arrows"); arrows != "" {
+		for _, a := range strings.Split(arrows, ",") {
+			arrow, err := parseArrow(a)
+			if err != nil {
+				return err
+			}
+			params.Arrows = append(params.Arrows, arrow)
+		}
+	}
+	return nil
+}
+
+// validateSquare makes sure a parameter holds a square in algebraic notation
+func validateSquare(name string, an string) error {
+	if _, ok := squares[an]; !ok {
+		return fmt.Errorf("%s must be a square like e4, not %q", name, an)
+	}
+	return nil
+}
+
+// parseEvaluation reads back an evaluation formatted by game.Evaluation.String
+func parseEvaluation(s string) (game.Evaluation, error) {
+	if strings.HasPrefix(s, "#") {
+		mate, err := strconv.Atoi(s[1:])
+		if err != nil || mate == 0 {
+			return game.Evaluation{}, fmt.Errorf("invalid evaluation %q", s)
+		}
+		return game.Evaluation{Mate: mate}, nil
+	}
+	pawns, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(pawns) || math.Abs(pawns) > maxEvalPawns {
+		return game.Evaluation{}, fmt.Errorf("invalid evaluation %q", s)
+	}
+	return game.Evaluation{CP: int(math.Round(pawns * 100))}, nil
+}
+
+// encode writes the board parameters into a URL query
+func (p BoardParams) encode(q url.Values) {
+	q.Add("fen", p.FEN)
+	q.Add("from", p.From)
+	q.Add("to", p.To)
+	q.Add("check", p.Check)
+	if p.Inverted {
+		q.Add("inverted", "true")
+	}
+	if p.Theme != "" {
+		q.Add("theme", p.Theme)
+	}
+	if p.Eval != nil {
+		q.Add("eval", p.Eval.String())
+	}
+	if len(p.Arrows) > 0 {
+		arrows := make([]string, len(p.Arrows))
+		for i, arrow := range p.Arrows {
+			arrows[i] = arrow.String()
+		}
+		q.Add("arrows", strings.Join(arrows, ","))
+	}
+}
+
+// width is the width of the drawn image, which grows when the evaluation bar is shown
+func (p BoardParams) width() int {
+	if p.Eval != nil {
+		return boardSize + evalBarWidth
+	}
+	return boardSize
+}
+
+// render draws the board described by the parameters as an image
+func (p BoardParams) render() (image.Image, error) {
+	c := newRasterCanvas(p.width(), boardSize)
+	if err := drawBoard(c, p); err != nil {
+		return nil, err
+	}
+	return c.img, nil
+}
+
+// renderSVG draws the board described by the parameters as an SVG document
+func (p BoardParams) renderSVG() ([]byte, error) {
+	c := newSVGCanvas(p.width(), boardSize)
+	if err := drawBoard(c, p); err != nil {
+		return nil, err
+	}
+	return c.bytes(), nil
+}
+
+// RenderPNG renders a snapshot of the game in-process and returns the PNG bytes
+func RenderPNG(snapshot game.Snapshot, options ...Option) ([]byte, error) {
+	image, err := paramsFromGame(snapshot, options...).render()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package rendering
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultCacheSize is the number of rendered images kept in memory when no size is given
+const DefaultCacheSize = 256
+
+// cacheMetrics are published on /debug/vars
+var cacheMetrics = expvar.NewMap("render_cache")
+
+func init() {
+	cacheMetrics.Set("hit_rate", expvar.Func(func() interface{} {
+		hits := counterValue("memory_hits") + counterValue("disk_hits")
+		total := hits + counterValue("misses")
+		if total == 0 {
+			return 0.0
+		}
+		return float64(hits) / float64(total)
+	}))
+}
+
+func counterValue(name string) int64 {
+	if v, ok := cacheMetrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// cachedImage is a rendered image together with its content type
+type cachedImage struct {
+	key         string
+	contentType string
+	data        []byte
+}
+
+// ImageCache is an LRU cache of rendered images with an optional disk tier.
+// Images are keyed by the canonical query of the link they were rendered for,
+// so the same link fetched repeatedly by Slack's unfurler is only rendered once
+type ImageCache struct {
+	size    int
+	dir     string
+	order   *list.List
+	entries map[string]*list.Element
+	sync.Mutex
+}
+
+// NewImageCache returns an ImageCache holding up to size images in memory.
+// When dir is not empty rendered images are also written there and survive restarts
+func NewImageCache(size int, dir string) (*ImageCache, error) {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &ImageCache{
+		size:    size,
+		dir:     dir,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// cacheKey hashes the format and every query parameter except the signature
+func cacheKey(format string, q url.Values) string {
+	canonical := url.Values{}
+	for key, val := range q {
+		if key != "signature" {
+			canonical[key] = val
+		}
+	}
+	sum := sha256.Sum256([]byte(format + "?" + canonical.Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a cached image from memory or from the disk tier
+func (c *ImageCache) Get(key string) (*cachedImage, bool) {
+	c.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		c.Unlock()
+		cacheMetrics.Add("memory_hits", 1)
+		return el.Value.(*cachedImage), true
+	}
+	c.Unlock()
+
+	if c.dir != "" {
+		if img, ok := c.readDisk(key); ok {
+			cacheMetrics.Add("disk_hits", 1)
+			c.add(img)
+			return img, true
+		}
+	}
+
+	cacheMetrics.Add("misses", 1)
+	return nil, false
+}
+
+// Put stores a rendered image in memory and in the disk tier
+func (c *ImageCache) Put(key string, contentType string, data []byte) {
+	img := &cachedImage{key: key, contentType: contentType, data: data}
+	c.add(img)
+	if c.dir != "" {
+		c.writeDisk(img)
+	}
+}
+
+// add puts the image at the front of the LRU list and evicts the least recently used ones
+func (c *ImageCache) add(img *cachedImage) {
+	c.Lock()
+	defer c.Unlock()
+
+	if el, ok := c.entries[img.key]; ok {
+		el.Value = img
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[img.key] = c.order.PushFront(img)
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedImage).key)
+		cacheMetrics.Add("evictions", 1)
+	}
+}
+
+// file names on disk carry the content type so it doesn't need to be stored separately
+var diskExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+	"image/gif":     ".gif",
+}
+
+func (c *ImageCache) readDisk(key string) (*cachedImage, bool) {
+	for contentType, ext := range diskExtensions {
+		data, err := ioutil.ReadFile(filepath.Join(c.dir, key+ext))
+		if err == nil {
+			return &cachedImage{key: key, contentType: contentType, data: data}, true
+		}
+	}
+	return nil, false
+}
+
+func (c *ImageCache) writeDisk(img *cachedImage) {
+	ext, ok := diskExtensions[img.contentType]
+	if !ok {
+		return
+	}
+	// write to a temporary file first so readers never see a partial image
+	path := filepath.Join(c.dir, img.key+ext)
+	tmp, err := ioutil.TempFile(c.dir, img.key)
+	if err != nil {
+		log.Println("could not write to the render cache:", err)
+		return
+	}
+	_, err = tmp.Write(img.data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		log.Println("could not write to the render cache:", err)
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,168 @@
+package rendering
+
+import (
+	"fmt"
+	"image/color"
+	"path/filepath"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	boardSize  = 512
+	squareSize = boardSize / 8
+	pieceRatio = 0.8
+	labelSize  = 14
+)
+
+// pieceAssets maps a piece to its image file in the piece set directory
+var pieceAssets = map[chess.Piece]string{
+	chess.BlackBishop: "bd.png",
+	chess.WhiteBishop: "bl.png",
+	chess.BlackKing:   "kd.png",
+	chess.WhiteKing:   "kl.png",
+	chess.BlackKnight: "nd.png",
+	chess.WhiteKnight: "nl.png",
+	chess.BlackPawn:   "pd.png",
+	chess.WhitePawn:   "pl.png",
+	chess.BlackQueen:  "qd.png",
+	chess.WhiteQueen:  "ql.png",
+	chess.BlackRook:   "rd.png",
+	chess.WhiteRook:   "rl.png",
+}
+
+// squares maps algebraic notation (e4, h8, etc) to a square
+var squares = func() map[string]chess.Square {
+	m := make(map[string]chess.Square, 64)
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		m[sq.String()] = sq
+	}
+	return m
+}()
+
+// canvas is a drawing surface. Both the SVG and the PNG renderers implement it
+// so that every board format is drawn by the same code.
+type canvas interface {
+	// rect fills a rectangle with a solid color
+	rect(x, y, w, h float64, c color.RGBA)
+	// image draws the image file at path scaled into a size x size square
+	image(x, y, size float64, path string) error
+	// text draws a string with its baseline starting at x, y
+	text(x, y float64, s string, c color.RGBA)
+	// polygon fills the closed shape through the points
+	polygon(points []point, c color.RGBA)
+}
+
+// point is a position on the canvas
+type point struct {
+	x, y float64
+}
+
+// squareOrigin returns the top left corner of a square on the drawn board
+func squareOrigin(sq chess.Square, inverted bool) (float64, float64) {
+	col, row := int(sq.File()), 7-int(sq.Rank())
+	if inverted {
+		col, row = 7-col, 7-row
+	}
+	return float64(col * squareSize), float64(row * squareSize)
+}
+
+// highlight fills the square given in algebraic notation, empty squares are ignored
+func highlight(c canvas, an string, inverted bool, clr color.RGBA) error {
+	if an == "" {
+		return nil
+	}
+	sq, ok := squares[an]
+	if !ok {
+		return fmt.Errorf("invalid square %q", an)
+	}
+	x, y := squareOrigin(sq, inverted)
+	c.rect(x, y, squareSize, squareSize, clr)
+	return nil
+}
+
+// drawBoard draws the position described by the parameters onto the canvas
+func drawBoard(c canvas, p BoardParams) error {
+	fen, err := chess.FEN(p.FEN)
+	if err != nil {
+		return err
+	}
+	position := chess.NewGame(fen).Position()
+	theme := themeOrDefault(p.Theme)
+
+	drawSquares(c, theme)
+
+	if err := highlight(c, p.From, p.Inverted, theme.Highlight); err != nil {
+		return err
+	}
+	if err := highlight(c, p.To, p.Inverted, theme.HighlightDim); err != nil {
+		return err
+	}
+	if err := highlight(c, p.Check, p.Inverted, theme.Check); err != nil {
+		return err
+	}
+
+	drawCoordinates(c, theme, p.Inverted)
+
+	pieceSize := squareSize * pieceRatio
+	pieceOffset := (squareSize - pieceSize) / 2
+	for sq, piece := range position.Board().SquareMap() {
+		x, y := squareOrigin(sq, p.Inverted)
+		if err := c.image(x+pieceOffset, y+pieceOffset, pieceSize, filepath.Join(theme.PieceDir, pieceAssets[piece])); err != nil {
+			return err
+		}
+	}
+
+	maxWeight := 1
+	for _, arrow := range p.Arrows {
+		if arrow.Weight > maxWeight {
+			maxWeight = arrow.Weight
+		}
+	}
+	for _, arrow := range p.Arrows {
+		if err := drawArrow(c, arrow, maxWeight, p.Inverted); err != nil {
+			return err
+		}
+	}
+
+	if p.Eval != nil {
+		drawEvalBar(c, *p.Eval, p.Inverted)
+	}
+	return nil
+}
+
+// drawSquares fills the 64 squares of an empty board
+func drawSquares(c canvas, theme Theme) {
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			clr := theme.Dark
+			if (col+row)%2 == 0 {
+				clr = theme.Light
+			}
+			c.rect(float64(col*squareSize), float64(row*squareSize), squareSize, squareSize, clr)
+		}
+	}
+}
+
+// drawCoordinates labels the files along the bottom edge and the ranks along the right edge
+func drawCoordinates(c canvas, theme Theme, inverted bool) {
+	files, ranks := "abcdefgh", "87654321"
+	if inverted {
+		files, ranks = "hgfedcba", "12345678"
+	}
+
+	for i, symbol := range files {
+		clr := theme.Dark
+		if i%2 == 0 {
+			clr = theme.Light
+		}
+		c.text(float64(squareSize*i+2), boardSize-3, string(symbol), clr)
+	}
+	for i, symbol := range ranks {
+		clr := theme.Dark
+		if i%2 == 0 {
+			clr = theme.Light
+		}
+		c.text(boardSize-10, float64(squareSize*i+labelSize-2), string(symbol), clr)
+	}
+}
@@ -0,0 +1,108 @@
+package rendering
+
+import (
+	_ "embed" // the coordinate font is compiled into the binary
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// labelFontData is the Go Bold font (BSD licensed, see fonts/LICENSE) used for rank and file labels
+//
+//go:embed fonts/Go-Bold.ttf
+var labelFontData []byte
+
+var labelFont = func() *opentype.Font {
+	f, err := opentype.Parse(labelFontData)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
+// newLabelFace returns a face for drawing labels onto raster images.
+// Faces are not safe for concurrent use so every canvas gets its own
+func newLabelFace() font.Face {
+	face, err := opentype.NewFace(labelFont, &opentype.FaceOptions{
+		Size:    labelSize,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+// textPath converts a string into SVG path data using the glyph outlines of the label font
+// so that SVG boards do not depend on the fonts installed on the viewer's machine
+func textPath(str string, x, y float64) (string, error) {
+	var buf sfnt.Buffer
+	var path strings.Builder
+	ppem := fixed.I(labelSize)
+	dot := fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+
+	for _, r := range str {
+		index, err := labelFont.GlyphIndex(&buf, r)
+		if err != nil {
+			return "", err
+		}
+		segments, err := labelFont.LoadGlyph(&buf, index, ppem, nil)
+		if err != nil {
+			return "", err
+		}
+
+		for i, seg := range segments {
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				if i > 0 {
+					path.WriteString("Z")
+				}
+				path.WriteString("M" + svgPoint(dot, seg.Args[0]))
+			case sfnt.SegmentOpLineTo:
+				path.WriteString("L" + svgPoint(dot, seg.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				path.WriteString("Q" + svgPoint(dot, seg.Args[0]) + " " + svgPoint(dot, seg.Args[1]))
+			case sfnt.SegmentOpCubeTo:
+				path.WriteString("C" + svgPoint(dot, seg.Args[0]) + " " + svgPoint(dot, seg.Args[1]) + " " + svgPoint(dot, seg.Args[2]))
+			}
+		}
+		if len(segments) > 0 {
+			path.WriteString("Z")
+		}
+
+		advance, err := labelFont.GlyphAdvance(&buf, index, ppem, font.HintingNone)
+		if err != nil {
+			return "", err
+		}
+		dot.X += advance
+	}
+	return path.String(), nil
+}
+
+// textWidth measures the advance width of a string drawn with the label font
+func textWidth(str string) float64 {
+	var buf sfnt.Buffer
+	var width fixed.Int26_6
+	for _, r := range str {
+		index, err := labelFont.GlyphIndex(&buf, r)
+		if err != nil {
+			continue
+		}
+		advance, err := labelFont.GlyphAdvance(&buf, index, fixed.I(labelSize), font.HintingNone)
+		if err != nil {
+			continue
+		}
+		width += advance
+	}
+	return float64(width) / 64
+}
+
+// svgPoint formats a glyph point offset by the dot position
+func svgPoint(dot, p fixed.Point26_6) string {
+	return fmt.Sprintf("%g,%g", float64(dot.X+p.X)/64, float64(dot.Y+p.Y)/64)
+}
@@ -2,58 +2,177 @@
 package rendering
 
 import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
 	"image/png"
 	"log"
 	"net/http"
-
-	"github.com/cjsaylor/chessimage"
+	"strings"
 )
 
 // BoardRenderHandler handles all image requests from Slack
 type BoardRenderHandler struct {
 	LinkRenderer RenderLink
+	// Cache keeps rendered boards around, nil disables caching
+	Cache *ImageCache
 }
 
-// ServeHTTP is a request handler
+// ServeHTTP is a request handler. /board.svg and /board.png always serve their own format,
+// any other path picks SVG or PNG based on the Accept header
 func (b BoardRenderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 	query := r.URL.Query()
-	fen := query.Get("fen")
-	if fen == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if query.Get("fen") == "" {
+		http.Error(w, "fen is required", http.StatusBadRequest)
 		return
 	}
 	if !b.LinkRenderer.ValidateLink(*r.URL) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	board, err := chessimage.NewRendererFromFEN(fen)
+	params, err := paramsFromQuery(query)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if from := query.Get("from"); from != "" {
-		to := query.Get("to")
-		tFrom, _ := chessimage.TileFromAN(from)
-		tTo, _ := chessimage.TileFromAN(to)
-		board.SetLastMove(chessimage.LastMove{
-			From: tFrom,
-			To:   tTo,
-		})
+
+	w.Header().Add("Vary", "Accept")
+	if wantsSVG(r) {
+		serveImage(w, r, b.Cache, "image/svg+xml", params.renderSVG)
+		return
 	}
-	if check := query.Get("check"); check != "" {
-		tCheck, _ := chessimage.TileFromAN(check)
-		board.SetCheckTile(tCheck)
+
+	serveImage(w, r, b.Cache, "image/png", func() ([]byte, error) {
+		image, err := params.render()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// serveImage writes the image for a validated link, using the cache when there is one.
+// Signed links always describe the same image so the cache key doubles as the ETag
+func serveImage(w http.ResponseWriter, r *http.Request, cache *ImageCache, contentType string, render func() ([]byte, error)) {
+	key := cacheKey(contentType, r.URL.Query())
+	etag := `"` + key + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "max-age=7776000")
+
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
 
-	inverted := query.Get("inverted") == "true"
-	image, err := board.Render(chessimage.Options{AssetPath: "./assets/", Inverted: inverted})
+	if cache != nil {
+		if img, ok := cache.Get(key); ok {
+			w.Header().Set("Content-Type", img.contentType)
+			w.Write(img.data)
+			return
+		}
+	}
+
+	data, err := safeRender(render)
 	if err != nil {
-		log.Println(err)
+		// a placeholder keeps Slack from showing a broken image, it must not be cached as the real one
+		log.Println("could not render the image:", err)
+		w.Header().Del("ETag")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", contentType)
+		switch contentType {
+		case "image/svg+xml":
+			w.Write(fallbackSVG())
+		case "image/gif":
+			w.Write(fallbackGIF())
+		default:
+			w.Write(fallbackPNG())
+		}
+		return
+	}
+	if cache != nil {
+		cache.Put(key, contentType, data)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+// safeRender turns a panic while drawing into an error so the fallback image gets served
+func safeRender(render func() ([]byte, error)) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while rendering: %v", r)
+		}
+	}()
+	return render()
+}
+
+// fallbackPNG is an empty board served when a board cannot be rendered
+func fallbackPNG() []byte {
+	c := newRasterCanvas(boardSize, boardSize)
+	drawSquares(c, themeOrDefault(DefaultTheme))
+	var buf bytes.Buffer
+	png.Encode(&buf, c.img)
+	return buf.Bytes()
+}
+
+// fallbackSVG is the SVG version of fallbackPNG
+func fallbackSVG() []byte {
+	c := newSVGCanvas(boardSize, boardSize)
+	drawSquares(c, themeOrDefault(DefaultTheme))
+	return c.bytes()
+}
+
+// fallbackGIF is a replay of a single frame showing the empty board of fallbackPNG
+func fallbackGIF() []byte {
+	c := newRasterCanvas(boardSize, boardSize)
+	theme := themeOrDefault(DefaultTheme)
+	drawSquares(c, theme)
+	paletted := image.NewPaletted(c.img.Bounds(), replayPalette(theme))
+	draw.Draw(paletted, paletted.Rect, c.img, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	gif.Encode(&buf, paletted, nil)
+	return buf.Bytes()
+}
+
+// matchesETag checks an If-None-Match header against the ETag of the image
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsSVG determines the image format for the request
+func wantsSVG(r *http.Request) bool {
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".svg"):
+		return true
+	case strings.HasSuffix(r.URL.Path, ".png"):
+		return false
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(accepted, ";")[0])
+		switch mediaType {
+		case "image/svg+xml":
+			return true
+		case "image/png":
+			return false
+		}
 	}
-	w.Header().Add("Cache-Control", "max-age=7776000")
-	png.Encode(w, image)
+	return false
 }
@@ -0,0 +1,168 @@
+package rendering
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestMain(m *testing.M) {
+	// the piece images are looked up relative to the repository root like in the server
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// serveBoard signs the query the way board links are signed and serves it from path
+func serveBoard(t testing.TB, path string, query url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	link := NewRenderLink("", "test-key")
+	query.Set("signature", link.sign(query))
+	r := httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	BoardRenderHandler{LinkRenderer: link}.ServeHTTP(w, r)
+	return w
+}
+
+func TestBoardRenderHandlerRendersSignedBoards(t *testing.T) {
+	w := serveBoard(t, "/board.png", url.Values{
+		"fen":      {startFEN},
+		"from":     {"e2"},
+		"to":       {"e4"},
+		"inverted": {"true"},
+		"eval":     {"+0.35"},
+		"arrows":   {"e2e4:3,d2d4"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("got content type %q", got)
+	}
+	if _, err := png.Decode(w.Body); err != nil {
+		t.Fatalf("the body is not a PNG: %v", err)
+	}
+}
+
+func TestBoardRenderHandlerRejectsUnsignedLinks(t *testing.T) {
+	query := url.Values{"fen": {startFEN}, "signature": {"0000"}}
+	r := httptest.NewRequest(http.MethodGet, "/board.png?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	BoardRenderHandler{LinkRenderer: NewRenderLink("", "test-key")}.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestBoardRenderHandlerRejectsBadParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"missing fen", url.Values{"from": {"e2"}, "to": {"e4"}}},
+		{"garbage fen", url.Values{"fen": {"not a fen"}}},
+		{"fen with too many ranks", url.Values{"fen": {"8/8/8/8/8/8/8/8/8 w - - 0 1"}}},
+		{"from without to", url.Values{"fen": {startFEN}, "from": {"e2"}}},
+		{"to without from", url.Values{"fen": {startFEN}, "to": {"e4"}}},
+		{"from off the board", url.Values{"fen": {startFEN}, "from": {"i9"}, "to": {"e4"}}},
+		{"to off the board", url.Values{"fen": {startFEN}, "from": {"e2"}, "to": {"e9"}}},
+		{"from and to the same", url.Values{"fen": {startFEN}, "from": {"e2"}, "to": {"e2"}}},
+		{"check off the board", url.Values{"fen": {startFEN}, "check": {"z1"}}},
+		{"inverted not a bool", url.Values{"fen": {startFEN}, "inverted": {"yes"}}},
+		{"unknown theme", url.Values{"fen": {startFEN}, "theme": {"nope"}}},
+		{"eval not a number", url.Values{"fen": {startFEN}, "eval": {"abc"}}},
+		{"eval out of bounds", url.Values{"fen": {startFEN}, "eval": {"+99999"}}},
+		{"eval mate not a number", url.Values{"fen": {startFEN}, "eval": {"#x"}}},
+		{"arrow not a move", url.Values{"fen": {startFEN}, "arrows": {"e2"}}},
+		{"arrow off the board", url.Values{"fen": {startFEN}, "arrows": {"e2e9"}}},
+		{"arrow weight zero", url.Values{"fen": {startFEN}, "arrows": {"e2e4:0"}}},
+		{"arrow weight too heavy", url.Values{"fen": {startFEN}, "arrows": {"e2e4:100000"}}},
+		{"empty arrow in list", url.Values{"fen": {startFEN}, "arrows": {"e2e4,"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveBoard(t, "/board.png", tt.query)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestServeImageFallsBackWhenRenderingPanics(t *testing.T) {
+	for _, contentType := range []string{"image/png", "image/svg+xml", "image/gif"} {
+		t.Run(contentType, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/board.png?fen=x", nil)
+			w := httptest.NewRecorder()
+			serveImage(w, r, nil, contentType, func() ([]byte, error) {
+				panic("broken board")
+			})
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != contentType {
+				t.Fatalf("got content type %q", got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Fatalf("the fallback must not be cached, got Cache-Control %q", got)
+			}
+			if w.Header().Get("ETag") != "" {
+				t.Fatal("the fallback must not carry the ETag of the real image")
+			}
+			want := map[string][]byte{"image/png": fallbackPNG(), "image/svg+xml": fallbackSVG(), "image/gif": fallbackGIF()}[contentType]
+			if !bytes.Equal(w.Body.Bytes(), want) {
+				t.Fatal("the body is not the fallback image")
+			}
+		})
+	}
+}
+
+func TestFallbackGIFIsAGIF(t *testing.T) {
+	anim, err := gif.DecodeAll(bytes.NewReader(fallbackGIF()))
+	if err != nil {
+		t.Fatalf("the replay fallback is not a GIF: %v", err)
+	}
+	if len(anim.Image) != 1 || anim.Image[0].Bounds().Dx() != boardSize {
+		t.Fatalf("got %d frames of %v, want a single board", len(anim.Image), anim.Image[0].Bounds())
+	}
+}
+
+func FuzzBoardRenderHandler(f *testing.F) {
+	f.Add(startFEN, "e2", "e4", "", "false", "", "")
+	f.Add(startFEN, "", "", "e1", "true", "e2e4:2,g1f3", "#-3")
+	f.Add("8/8/8/8/8/8/8/8 w - - 0 1", "a1", "h8", "", "", "a1h8", "+1000.00")
+	f.Add("not a fen", "e2", "", "z9", "maybe", "e2e4:0", "abc")
+
+	f.Fuzz(func(t *testing.T, fen, from, to, check, inverted, arrows, eval string) {
+		query := url.Values{"fen": {fen}}
+		for key, value := range map[string]string{"from": from, "to": to, "check": check, "inverted": inverted, "arrows": arrows, "eval": eval} {
+			if value != "" {
+				query.Set(key, value)
+			}
+		}
+
+		w := serveBoard(t, "/board.png", query)
+		switch w.Code {
+		case http.StatusBadRequest:
+		case http.StatusOK:
+			if got := w.Header().Get("Content-Type"); got != "image/png" {
+				t.Fatalf("got content type %q", got)
+			}
+			if _, err := png.Decode(w.Body); err != nil {
+				t.Fatalf("the body is not a PNG: %v", err)
+			}
+		default:
+			t.Fatalf("got status %d for %v", w.Code, query)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package rendering
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/game"
+)
+
+const (
+	evalBarWidth   = 40
+	arrowWidth     = 12
+	minArrowWidth  = 4
+	maxArrowWeight = 1000
+)
+
+var (
+	colorArrow       = color.RGBA{21, 120, 27, 170}
+	colorEvalWhite   = color.RGBA{240, 240, 240, 255}
+	colorEvalBlack   = color.RGBA{40, 40, 40, 255}
+	colorEvalDivider = color.RGBA{128, 128, 128, 255}
+)
+
+// squareCenter returns the middle of a square given in algebraic notation
+func squareCenter(an string, inverted bool) (point, error) {
+	sq, ok := squares[an]
+	if !ok {
+		return point{}, fmt.Errorf("invalid square %q", an)
+	}
+	x, y := squareOrigin(sq, inverted)
+	return point{x + squareSize/2, y + squareSize/2}, nil
+}
+
+// Arrow is a move drawn on top of the board
+type Arrow struct {
+	// Move is in UCI notation (e2e4, etc)
+	Move string
+	// Weight scales the thickness of the arrow relative to the heaviest arrow on the board
+	Weight int
+}
+
+// String formats the arrow for board URLs, like e2e4 or e2e4:3
+func (a Arrow) String() string {
+	if a.Weight <= 1 {
+		return a.Move
+	}
+	return fmt.Sprintf("%s:%d", a.Move, a.Weight)
+}
+
+// parseArrow reads back an arrow formatted by Arrow.String
+func parseArrow(s string) (Arrow, error) {
+	parts := strings.SplitN(s, ":", 2)
+	arrow := Arrow{Move: parts[0], Weight: 1}
+	if len(arrow.Move) != 4 {
+		return Arrow{}, fmt.Errorf("invalid arrow %q", s)
+	}
+	from, fromOK := squares[arrow.Move[0:2]]
+	to, toOK := squares[arrow.Move[2:4]]
+	if !fromOK || !toOK || from == to {
+		return Arrow{}, fmt.Errorf("invalid arrow %q", s)
+	}
+	if len(parts) == 2 {
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 1 || weight > maxArrowWeight {
+			return Arrow{}, fmt.Errorf("invalid arrow weight %q", s)
+		}
+		arrow.Weight = weight
+	}
+	return arrow, nil
+}
+
+// drawArrow draws an arrow for a move. The heaviest arrow on the board gets the full width
+func drawArrow(c canvas, arrow Arrow, maxWeight int, inverted bool) error {
+	move := arrow.Move
+	if len(move) < 4 {
+		return fmt.Errorf("invalid arrow %q", move)
+	}
+	from, err := squareCenter(move[0:2], inverted)
+	if err != nil {
+		return err
+	}
+	to, err := squareCenter(move[2:4], inverted)
+	if err != nil {
+		return err
+	}
+
+	dx, dy := to.x-from.x, to.y-from.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return fmt.Errorf("invalid arrow %q", move)
+	}
+	// unit vector along the arrow and its normal
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy, ux
+
+	width := math.Max(arrowWidth*float64(arrow.Weight)/float64(maxWeight), minArrowWidth)
+	headLength := math.Min(width*2.5, length/2)
+	headWidth := width * 2.5
+	neck := point{to.x - ux*headLength, to.y - uy*headLength}
+	offset := func(p point, w float64) point {
+		return point{p.x + nx*w/2, p.y + ny*w/2}
+	}
+
+	c.polygon([]point{
+		offset(from, width),
+		offset(neck, width),
+		offset(neck, headWidth),
+		to,
+		offset(neck, -headWidth),
+		offset(neck, -width),
+		offset(from, -width),
+	}, colorArrow)
+	return nil
+}
+
+// whiteShare converts an evaluation into the share of the bar that belongs to White
+func whiteShare(e game.Evaluation) float64 {
+	switch {
+	case e.Mate > 0:
+		return 1
+	case e.Mate < 0:
+		return 0
+	}
+	// logistic curve so that a few pawns of advantage already fill most of the bar
+	return 1 / (1 + math.Exp(-0.00368208*float64(e.CP)))
+}
+
+// evalLabel is the short form of an evaluation that fits on the bar
+func evalLabel(e game.Evaluation) string {
+	if e.Mate != 0 {
+		return fmt.Sprintf("M%d", int(math.Abs(float64(e.Mate))))
+	}
+	return fmt.Sprintf("%.1f", math.Abs(float64(e.CP))/100)
+}
+
+// drawEvalBar draws the evaluation bar to the right of the board with White's side next to White's pieces
+func drawEvalBar(c canvas, e game.Evaluation, inverted bool) {
+	share := whiteShare(e)
+	whiteHeight := share * boardSize
+	x := float64(boardSize)
+
+	whiteY, blackY := boardSize-whiteHeight, 0.0
+	if inverted {
+		whiteY, blackY = 0, whiteHeight
+	}
+	c.rect(x, blackY, evalBarWidth, boardSize-whiteHeight, colorEvalBlack)
+	c.rect(x, whiteY, evalBarWidth, whiteHeight, colorEvalWhite)
+	c.rect(x, boardSize/2-1, evalBarWidth, 2, colorEvalDivider)
+
+	// the label goes on the end of the side that is ahead
+	label := evalLabel(e)
+	labelX := x + (evalBarWidth-textWidth(label))/2
+	whiteAhead := share >= 0.5
+	if whiteAhead != inverted {
+		c.text(labelX, boardSize-6, label, labelColor(whiteAhead))
+	} else {
+		c.text(labelX, labelSize+4, label, labelColor(whiteAhead))
+	}
+}
+
+// labelColor contrasts the label with the part of the bar it is drawn on
+func labelColor(whiteAhead bool) color.RGBA {
+	if whiteAhead {
+		return colorEvalBlack
+	}
+	return colorEvalWhite
+}
@@ -0,0 +1,65 @@
+package rendering
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// rasterCanvas draws onto an in-memory RGBA image
+type rasterCanvas struct {
+	img  *image.RGBA
+	face font.Face
+}
+
+func newRasterCanvas(w, h int) *rasterCanvas {
+	return &rasterCanvas{
+		img:  image.NewRGBA(image.Rect(0, 0, w, h)),
+		face: newLabelFace(),
+	}
+}
+
+func (r *rasterCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	bounds := image.Rect(int(x), int(y), int(x+w), int(y+h))
+	draw.Draw(r.img, bounds, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+func (r *rasterCanvas) image(x, y, size float64, path string) error {
+	asset, err := loadAsset(path)
+	if err != nil {
+		return err
+	}
+
+	src := asset.resized(int(size))
+	bounds := src.Bounds().Add(image.Pt(int(x), int(y)))
+	draw.Draw(r.img, bounds, src, image.Point{}, draw.Over)
+	return nil
+}
+
+func (r *rasterCanvas) text(x, y float64, s string, c color.RGBA) {
+	d := font.Drawer{
+		Dst:  r.img,
+		Src:  image.NewUniform(c),
+		Face: r.face,
+		Dot:  fixed.P(int(x), int(y)),
+	}
+	d.DrawString(s)
+}
+
+func (r *rasterCanvas) polygon(points []point, c color.RGBA) {
+	if len(points) == 0 {
+		return
+	}
+	bounds := r.img.Bounds()
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	z.MoveTo(float32(points[0].x), float32(points[0].y))
+	for _, p := range points[1:] {
+		z.LineTo(float32(p.x), float32(p.y))
+	}
+	z.ClosePath()
+	z.Draw(r.img, bounds, image.NewUniform(c), image.Point{})
+}
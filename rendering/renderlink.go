@@ -5,10 +5,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/dyslexicat/collab-chess/game"
-
-	"github.com/notnil/chess"
 )
 
 // RenderLink is a simple struct for creating valid external board URLs
@@ -17,39 +16,70 @@ type RenderLink struct {
 	signingKey string
 }
 
-// CreateLink returns an externally accessible board URL at the current game state
-func (r RenderLink) CreateLink(gm *game.Game) (*url.URL, error) {
-	fen := gm.FEN()
-	sig := sha256.New()
-	sig.Write([]byte(fen + r.signingKey))
-	from, to, check := "", "", ""
-	if lastMove := gm.LastMove(); lastMove != nil {
-		from = lastMove.S1().String()
-		to = lastMove.S2().String()
-		if lastMove.HasTag(chess.Check) {
-			square := gm.CheckedKing()
-			check = square.String()
-		}
-	}
+// CreateLink returns an externally accessible board URL of a snapshot of the game
+func (r RenderLink) CreateLink(snapshot game.Snapshot, options ...Option) (*url.URL, error) {
 	u, _ := url.Parse(fmt.Sprintf("%v/board.png", r.hostName))
 	q := u.Query()
-	q.Add("fen", fen)
-	q.Add("signature", hex.EncodeToString(sig.Sum(nil)))
-	q.Add("from", from)
-	q.Add("to", to)
-	q.Add("check", check)
-	if gm.Turn() == game.Black {
-		q.Add("inverted", "true")
-	}
+	paramsFromGame(snapshot, options...).encode(q)
+	q.Add("signature", r.sign(q))
 	u.RawQuery = q.Encode()
 	return u, nil
 }
 
+// CreateReplayLink returns an externally accessible URL of an animated replay of the game so far
+func (r RenderLink) CreateReplayLink(snapshot game.Snapshot, delay time.Duration, options ...Option) (*url.URL, error) {
+	u, _ := url.Parse(fmt.Sprintf("%v/replay.gif", r.hostName))
+	q := u.Query()
+	replayFromGame(snapshot, delay, options...).encode(q)
+	q.Add("signature", r.sign(q))
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// CreateRecordLinks returns an externally accessible board URL for every position of a finished game,
+// starting with the initial position
+func (r RenderLink) CreateRecordLinks(record game.Record, options ...Option) ([]*url.URL, error) {
+	replay := ReplayParams{Moves: record.Moves}
+	replay.Board.Inverted = record.HumanColor == game.Black
+	for _, option := range options {
+		option(&replay.Board)
+	}
+
+	frames, err := replay.frames()
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]*url.URL, 0, len(frames))
+	for _, frame := range frames {
+		u, _ := url.Parse(fmt.Sprintf("%v/board.png", r.hostName))
+		q := u.Query()
+		frame.encode(q)
+		q.Add("signature", r.sign(q))
+		u.RawQuery = q.Encode()
+		links = append(links, u)
+	}
+	return links, nil
+}
+
 // ValidateLink ensures that the link signatuer is signed properly with the app signing key
 func (r RenderLink) ValidateLink(url url.URL) bool {
+	q := url.Query()
+	return r.sign(q) == q.Get("signature")
+}
+
+// sign hashes every query parameter except the signature itself so that
+// none of the drawing options can be changed without invalidating the link
+func (r RenderLink) sign(q url.Values) string {
+	canonical := url.Values{}
+	for key, val := range q {
+		if key != "signature" {
+			canonical[key] = val
+		}
+	}
 	sig := sha256.New()
-	sig.Write([]byte(url.Query().Get("fen") + r.signingKey))
-	return hex.EncodeToString(sig.Sum(nil)) == url.Query().Get("signature")
+	sig.Write([]byte(canonical.Encode() + r.signingKey))
+	return hex.EncodeToString(sig.Sum(nil))
 }
 
 // NewRenderLink creates a new RenderLink struct instance
@@ -0,0 +1,236 @@
+package rendering
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// DefaultReplayDelay is the time each position is shown for in a replay
+	DefaultReplayDelay = time.Second
+	// MinReplayDelay and MaxReplayDelay bound the delays replay links are accepted with
+	MinReplayDelay = 100 * time.Millisecond
+	MaxReplayDelay = 10 * time.Second
+	// the final position stays on screen a little longer before the animation loops
+	finalFrameFactor = 3
+	// a generous limit on the game length that keeps a single request from rendering forever
+	maxReplayMoves = 600
+)
+
+// ReplayParams holds everything needed to animate a whole game
+type ReplayParams struct {
+	// Moves are the moves of the game in UCI notation (e2e4, e7e8q, etc)
+	Moves []string
+	// Delay is how long each position is shown for
+	Delay time.Duration
+	// Board holds the options shared by every frame (theme, orientation)
+	Board BoardParams
+}
+
+// replayFromGame collects the replay parameters for the moves of the game so far.
+// Like single boards, replays are drawn from the perspective of the human players
+func replayFromGame(snapshot game.Snapshot, delay time.Duration, options ...Option) ReplayParams {
+	replay := ReplayParams{Delay: delay}
+	position := chess.StartingPosition()
+	for _, move := range snapshot.Moves {
+		replay.Moves = append(replay.Moves, chess.UCINotation{}.Encode(position, move))
+		position = position.Update(move)
+	}
+
+	replay.Board.Inverted = snapshot.HumanColor == game.Black
+	for _, option := range options {
+		option(&replay.Board)
+	}
+	return replay
+}
+
+// replayFromQuery reads the replay parameters back from a replay URL query
+// and rejects anything that doesn't describe a playable game
+func replayFromQuery(query url.Values) (ReplayParams, error) {
+	replay := ReplayParams{Delay: DefaultReplayDelay}
+	if err := parseDrawOptions(query, &replay.Board); err != nil {
+		return replay, err
+	}
+	if moves := query.Get("moves"); moves != "" {
+		replay.Moves = strings.Split(moves, ",")
+	}
+	if len(replay.Moves) > maxReplayMoves {
+		return replay, fmt.Errorf("replays are limited to %d moves", maxReplayMoves)
+	}
+	if delay := query.Get("delay"); delay != "" {
+		ms, err := strconv.Atoi(delay)
+		if err != nil {
+			return replay, fmt.Errorf("invalid delay %q", delay)
+		}
+		replay.Delay = time.Duration(ms) * time.Millisecond
+	}
+	if replay.Delay < MinReplayDelay || replay.Delay > MaxReplayDelay {
+		return replay, fmt.Errorf("delay must be between %v and %v", MinReplayDelay, MaxReplayDelay)
+	}
+	// replaying the moves catches illegal ones before anything is drawn
+	if _, err := replay.frames(); err != nil {
+		return replay, err
+	}
+	return replay, nil
+}
+
+// encode writes the replay parameters into a URL query
+func (rp ReplayParams) encode(q url.Values) {
+	q.Add("moves", strings.Join(rp.Moves, ","))
+	q.Add("delay", strconv.Itoa(int(rp.Delay/time.Millisecond)))
+	if rp.Board.Inverted {
+		q.Add("inverted", "true")
+	}
+	if rp.Board.Theme != "" {
+		q.Add("theme", rp.Board.Theme)
+	}
+}
+
+// frames returns the board parameters of every position of the game, starting with the initial position
+func (rp ReplayParams) frames() ([]BoardParams, error) {
+	position := chess.StartingPosition()
+	frame := rp.Board
+	frame.FEN = position.String()
+	frames := []BoardParams{frame}
+
+	for _, uci := range rp.Moves {
+		move, err := legalMove(position, uci)
+		if err != nil {
+			return nil, err
+		}
+		position = position.Update(move)
+
+		frame := rp.Board
+		frame.FEN = position.String()
+		frame.From = move.S1().String()
+		frame.To = move.S2().String()
+		if move.HasTag(chess.Check) {
+			frame.Check = kingSquare(position, position.Turn()).String()
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// render draws every position of the game as an animated GIF
+func (rp ReplayParams) render() (*gif.GIF, error) {
+	frames, err := rp.frames()
+	if err != nil {
+		return nil, err
+	}
+
+	pal := replayPalette(themeOrDefault(rp.Board.Theme))
+	delay := int(rp.Delay / (10 * time.Millisecond))
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		img, err := frame.render()
+		if err != nil {
+			return nil, err
+		}
+		paletted := image.NewPaletted(img.Bounds(), pal)
+		draw.Draw(paletted, paletted.Rect, img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	anim.Delay[len(anim.Delay)-1] = delay * finalFrameFactor
+	return anim, nil
+}
+
+// replayPalette puts the exact theme colors first so squares don't get dithered,
+// followed by a gray ramp for the pieces and a general purpose palette for everything else
+func replayPalette(theme Theme) color.Palette {
+	pal := color.Palette{theme.Light, theme.Dark, theme.Highlight, theme.HighlightDim, theme.Check}
+	for i := 0; i < 32; i++ {
+		v := uint8(i * 255 / 31)
+		pal = append(pal, color.RGBA{v, v, v, 255})
+	}
+	return append(pal, palette.Plan9[:256-len(pal)]...)
+}
+
+// legalMove finds the move given in UCI notation among the valid moves of the position.
+// Unlike decoding the notation on its own this also rejects illegal moves and sets the check tags
+func legalMove(position *chess.Position, uci string) (*chess.Move, error) {
+	decoded, err := chess.UCINotation{}.Decode(position, uci)
+	if err != nil {
+		return nil, fmt.Errorf("invalid move %q", uci)
+	}
+	for _, move := range position.ValidMoves() {
+		if move.S1() == decoded.S1() && move.S2() == decoded.S2() && move.Promo() == decoded.Promo() {
+			return move, nil
+		}
+	}
+	return nil, fmt.Errorf("illegal move %q", uci)
+}
+
+// kingSquare returns the square of the king of the given color
+func kingSquare(position *chess.Position, c chess.Color) chess.Square {
+	for sq, piece := range position.Board().SquareMap() {
+		if piece.Type() == chess.King && piece.Color() == c {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
+
+// RenderGIF renders every position of the game so far as an animated GIF and returns its bytes
+func RenderGIF(snapshot game.Snapshot, delay time.Duration, options ...Option) ([]byte, error) {
+	anim, err := replayFromGame(snapshot, delay, options...).render()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReplayRenderHandler handles animated replay requests
+type ReplayRenderHandler struct {
+	LinkRenderer RenderLink
+	// Cache keeps rendered replays around, nil disables caching
+	Cache *ImageCache
+}
+
+// ServeHTTP is a request handler
+func (h ReplayRenderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.LinkRenderer.ValidateLink(*r.URL) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	replay, err := replayFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serveImage(w, r, h.Cache, "image/gif", func() ([]byte, error) {
+		anim, err := replay.render()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
@@ -0,0 +1,66 @@
+package rendering
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"log"
+)
+
+// svgCanvas writes the drawing as SVG elements
+type svgCanvas struct {
+	buf bytes.Buffer
+}
+
+func newSVGCanvas(w, h int) *svgCanvas {
+	s := &svgCanvas{}
+	fmt.Fprintf(&s.buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`, w, h, w, h)
+	return s
+}
+
+func (s *svgCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	fmt.Fprintf(&s.buf, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`, x, y, w, h, svgColor(c))
+}
+
+func (s *svgCanvas) image(x, y, size float64, path string) error {
+	asset, err := loadAsset(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&s.buf, `<image x="%g" y="%g" width="%g" height="%g" xlink:href="data:image/png;base64,%s"/>`, x, y, size, size, asset.encoded)
+	return nil
+}
+
+func (s *svgCanvas) text(x, y float64, str string, c color.RGBA) {
+	path, err := textPath(str, x, y)
+	if err != nil {
+		log.Println("could not draw text:", err)
+		return
+	}
+	fmt.Fprintf(&s.buf, `<path d="%s" fill="%s"/>`, path, svgColor(c))
+}
+
+func (s *svgCanvas) polygon(points []point, c color.RGBA) {
+	s.buf.WriteString(`<polygon points="`)
+	for i, p := range points {
+		if i > 0 {
+			s.buf.WriteString(" ")
+		}
+		fmt.Fprintf(&s.buf, "%g,%g", p.x, p.y)
+	}
+	fmt.Fprintf(&s.buf, `" fill="%s"/>`, svgColor(c))
+}
+
+// bytes closes the document and returns the SVG source
+func (s *svgCanvas) bytes() []byte {
+	s.buf.WriteString("</svg>")
+	return s.buf.Bytes()
+}
+
+// svgColor formats a color as an SVG fill value
+func svgColor(c color.RGBA) string {
+	if c.A == 255 {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3f)", c.R, c.G, c.B, float64(c.A)/255)
+}
@@ -0,0 +1,75 @@
+package rendering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/notnil/chess"
+)
+
+// unicodePieces maps a piece to its Unicode chess symbol
+var unicodePieces = map[chess.Piece]string{
+	chess.WhiteKing:   "♔",
+	chess.WhiteQueen:  "♕",
+	chess.WhiteRook:   "♖",
+	chess.WhiteBishop: "♗",
+	chess.WhiteKnight: "♘",
+	chess.WhitePawn:   "♙",
+	chess.BlackKing:   "♚",
+	chess.BlackQueen:  "♛",
+	chess.BlackRook:   "♜",
+	chess.BlackBishop: "♝",
+	chess.BlackKnight: "♞",
+	chess.BlackPawn:   "♟",
+}
+
+// TextBoard draws a snapshot of the game with Unicode pieces for clients that can't show images.
+// The board has coordinates and is followed by the last move, check and whose turn it is
+func TextBoard(snapshot game.Snapshot, options ...Option) string {
+	params := paramsFromGame(snapshot, options...)
+	squareMap := snapshot.Position.Board().SquareMap()
+
+	files := []chess.File{chess.FileA, chess.FileB, chess.FileC, chess.FileD, chess.FileE, chess.FileF, chess.FileG, chess.FileH}
+	ranks := []chess.Rank{chess.Rank8, chess.Rank7, chess.Rank6, chess.Rank5, chess.Rank4, chess.Rank3, chess.Rank2, chess.Rank1}
+	if params.Inverted {
+		for i, j := 0, 7; i < j; i, j = i+1, j-1 {
+			files[i], files[j] = files[j], files[i]
+			ranks[i], ranks[j] = ranks[j], ranks[i]
+		}
+	}
+
+	var b strings.Builder
+	for _, rank := range ranks {
+		b.WriteString(rank.String())
+		for _, file := range files {
+			sq := chess.Square(int(rank)*8 + int(file))
+			piece, ok := squareMap[sq]
+			switch {
+			case ok:
+				b.WriteString(" " + unicodePieces[piece])
+			case (int(file)+int(rank))%2 == 0:
+				// dark squares
+				b.WriteString(" ·")
+			default:
+				b.WriteString("  ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(" ")
+	for _, file := range files {
+		b.WriteString(" " + file.String())
+	}
+	b.WriteString("\n")
+
+	if san := snapshot.LastMoveSAN; san != "" {
+		fmt.Fprintf(&b, "\nLast move: %s (%s-%s)", san, params.From, params.To)
+	}
+	if params.Check != "" {
+		fmt.Fprintf(&b, "\nCheck! The king on %s is attacked", params.Check)
+	}
+	fmt.Fprintf(&b, "\n%s to move", snapshot.Turn)
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package rendering
+
+import (
+	"image/color"
+	"sort"
+	"sync"
+)
+
+// DefaultTheme is used whenever no theme or an unknown theme is requested
+const DefaultTheme = "brown"
+
+// Theme is a board color scheme together with the piece set it is drawn with
+type Theme struct {
+	Name         string
+	Light        color.RGBA
+	Dark         color.RGBA
+	Highlight    color.RGBA
+	HighlightDim color.RGBA
+	Check        color.RGBA
+	// PieceDir is the directory holding the piece images (pl.png, kd.png, etc)
+	PieceDir string
+}
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]Theme{
+		"brown": {
+			Name:         "brown",
+			Light:        color.RGBA{239, 218, 183, 255},
+			Dark:         color.RGBA{180, 135, 102, 255},
+			Highlight:    color.RGBA{205, 210, 122, 255},
+			HighlightDim: color.RGBA{170, 160, 75, 255},
+			Check:        color.RGBA{227, 30, 32, 255},
+			PieceDir:     "./assets/",
+		},
+		"green": {
+			Name:         "green",
+			Light:        color.RGBA{238, 238, 210, 255},
+			Dark:         color.RGBA{118, 150, 86, 255},
+			Highlight:    color.RGBA{246, 246, 130, 255},
+			HighlightDim: color.RGBA{186, 202, 68, 255},
+			Check:        color.RGBA{227, 30, 32, 255},
+			PieceDir:     "./assets/",
+		},
+		"blue": {
+			Name:         "blue",
+			Light:        color.RGBA{222, 227, 230, 255},
+			Dark:         color.RGBA{140, 162, 173, 255},
+			Highlight:    color.RGBA{155, 199, 227, 255},
+			HighlightDim: color.RGBA{104, 155, 192, 255},
+			Check:        color.RGBA{227, 30, 32, 255},
+			PieceDir:     "./assets/",
+		},
+		"gray": {
+			Name:         "gray",
+			Light:        color.RGBA{220, 220, 220, 255},
+			Dark:         color.RGBA{150, 150, 150, 255},
+			Highlight:    color.RGBA{230, 215, 140, 255},
+			HighlightDim: color.RGBA{190, 170, 90, 255},
+			Check:        color.RGBA{227, 30, 32, 255},
+			PieceDir:     "./assets/",
+		},
+	}
+)
+
+// RegisterTheme adds a theme to the registry or replaces an existing one with the same name
+func RegisterTheme(t Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[t.Name] = t
+}
+
+// LookupTheme returns the theme registered under the name
+func LookupTheme(name string) (Theme, bool) {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	t, ok := themes[name]
+	return t, ok
+}
+
+// ThemeNames returns the names of all registered themes in alphabetical order
+func ThemeNames() []string {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// themeOrDefault returns the named theme and falls back to the default theme for unknown names
+func themeOrDefault(name string) Theme {
+	if t, ok := LookupTheme(name); ok {
+		return t
+	}
+	t, _ := LookupTheme(DefaultTheme)
+	return t
+}
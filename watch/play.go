@@ -0,0 +1,257 @@
+package watch
+
+import (
+	"crypto/subtle"
+	"embed"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// joinTimeout is how long a client has to send its join message after connecting
+	joinTimeout = 10 * time.Second
+	// pingInterval is how often clients are pinged, they are dropped when a pong doesn't arrive in pongTimeout
+	pingInterval = 30 * time.Second
+	pongTimeout  = 60 * time.Second
+	writeTimeout = 10 * time.Second
+	// maxClientMessage bounds what clients can send, a vote is only a few bytes
+	maxClientMessage = 1024
+	// webPlayerPrefix sets the IDs of web players apart from Slack user IDs
+	webPlayerPrefix = "web:"
+)
+
+// playerNameRegex limits the names web players pick to something safe to show everywhere
+var playerNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,20}$`)
+
+//go:embed templates/play.html
+var playFS embed.FS
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// PlayHandler lets people without Slack vote on the active game.
+// It serves a small web client under /play and the WebSocket it talks to at /play/ws
+type PlayHandler struct {
+	Handler
+	// Token is the code web players need to join, an empty token turns web voting off.
+	// Every socket votes once per turn, but nothing stops a holder of the token from opening
+	// several sockets under different names and voting once with each of them, so the token
+	// should only be handed to people trusted with as many votes as they like
+	Token string
+}
+
+// clientMessage is a message sent by a web client, either joining with a name or voting on a move
+type clientMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Move  string `json:"move,omitempty"`
+}
+
+// serverMessage is a message sent to web clients, only the fields of its type are set
+type serverMessage struct {
+	Type        string `json:"type"`
+	PlayerID    string `json:"player_id,omitempty"`
+	State       *state `json:"state,omitempty"`
+	SecondsLeft *int   `json:"seconds_left,omitempty"`
+	Move        string `json:"move,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (h PlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/play":
+		data, err := playFS.ReadFile("templates/play.html")
+		if err != nil {
+			http.Error(w, "could not load the page", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	case "/play/ws":
+		h.serveSocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// client is a joined web player, writes are serialized since a connection supports only one writer
+type client struct {
+	conn     *websocket.Conn
+	playerID string
+	sync.Mutex
+}
+
+func (c *client) send(msg serverMessage) error {
+	c.Lock()
+	defer c.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *client) ping() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+func (h PlayHandler) serveSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already answered with an error
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxClientMessage)
+
+	c, err := h.join(conn)
+	if err != nil {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		conn.WriteJSON(serverMessage{Type: "error", Error: err.Error()})
+		return
+	}
+	log.Println(c.playerID, "joined from the web")
+
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readVotes(c)
+	}()
+	h.writeEvents(c, done)
+}
+
+// join waits for the join message and checks its token and name
+func (h PlayHandler) join(conn *websocket.Conn) (*client, error) {
+	conn.SetReadDeadline(time.Now().Add(joinTimeout))
+	var msg clientMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "join" {
+		return nil, fmt.Errorf("the first message must be a join message")
+	}
+	if subtle.ConstantTimeCompare([]byte(msg.Token), []byte(h.Token)) != 1 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if !playerNameRegex.MatchString(msg.Name) {
+		return nil, fmt.Errorf("names are 1 to 20 letters, digits, - or _")
+	}
+
+	c := &client{conn: conn, playerID: webPlayerPrefix + msg.Name}
+	if err := c.send(serverMessage{Type: "joined", PlayerID: c.playerID}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readVotes votes on behalf of the client until the connection is closed.
+// Votes take the same path as the !move messages in Slack
+func (h PlayHandler) readVotes(c *client) {
+	for {
+		var msg clientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type != "vote" {
+			c.send(serverMessage{Type: "error", Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+			continue
+		}
+
+		gm, err := h.GameStorage.RetrieveGame()
+		if err != nil {
+			c.send(serverMessage{Type: "error", Error: "There isn't an active game at the moment"})
+			continue
+		}
+		if gm.TurnPlayer().ID == "chessbot" {
+			c.send(serverMessage{Type: "error", Error: "It is the bot's turn"})
+			continue
+		}
+		// the first vote of the turn counts, later ones would only look like they did
+		if voted, ok := gm.Votes()[c.playerID]; ok {
+			c.send(serverMessage{Type: "error", Error: fmt.Sprintf("You already voted for %s this turn", voted)})
+			continue
+		}
+		if err := gm.Vote(c.playerID, msg.Move); err != nil {
+			c.send(serverMessage{Type: "error", Error: fmt.Sprintf("%s is not a valid move", msg.Move)})
+			continue
+		}
+		c.send(serverMessage{Type: "voted", Move: msg.Move})
+	}
+}
+
+// writeEvents sends the state of the active game whenever it changes and the countdown every second.
+// It follows the games as they start and end until the client goes away
+func (h PlayHandler) writeEvents(c *client, done <-chan struct{}) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	pinger := time.NewTicker(pingInterval)
+	defer pinger.Stop()
+
+	var (
+		current     *game.Game
+		events      <-chan game.Event
+		unsubscribe = func() {}
+	)
+	defer func() { unsubscribe() }()
+
+	// follow switches to the game in play and tells the client about it
+	follow := func() error {
+		gm, err := h.GameStorage.RetrieveGame()
+		if err != nil {
+			gm = nil
+		}
+		if gm == current {
+			return nil
+		}
+
+		unsubscribe()
+		current, events, unsubscribe = gm, nil, func() {}
+		if gm == nil {
+			return c.send(serverMessage{Type: "no_game"})
+		}
+		events, unsubscribe = gm.Subscribe()
+		s := h.state(gm.Snapshot())
+		return c.send(serverMessage{Type: "state", State: &s})
+	}
+
+	if err := follow(); err != nil {
+		return
+	}
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-events:
+			s := h.state(current.Snapshot())
+			err = c.send(serverMessage{Type: "state", State: &s})
+		case <-ticker.C:
+			if err = follow(); err == nil && current != nil {
+				err = c.send(serverMessage{Type: "tick", SecondsLeft: secondsLeft(current.Snapshot())})
+			}
+		case <-pinger.C:
+			err = c.ping()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
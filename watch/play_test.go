@@ -0,0 +1,233 @@
+package watch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+
+	"github.com/gorilla/websocket"
+)
+
+const testToken = "event-code"
+
+// newPlayServer serves a play handler over the store of newTestHandler
+func newPlayServer(t *testing.T) (*httptest.Server, PlayHandler, *game.Game) {
+	t.Helper()
+	h, gm := newTestHandler(t)
+	play := PlayHandler{Handler: h, Token: testToken}
+	server := httptest.NewServer(play)
+	t.Cleanup(server.Close)
+	return server, play, gm
+}
+
+// dial opens a WebSocket to the play handler
+func dial(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/play/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive reads the next message of the given type, skipping the countdown
+func receive(t *testing.T, conn *websocket.Conn, msgType string) serverMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg serverMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("got no %s message: %v", msgType, err)
+		}
+		if msg.Type == "tick" && msgType != "tick" {
+			continue
+		}
+		if msg.Type != msgType {
+			t.Fatalf("got %+v, want a %s message", msg, msgType)
+		}
+		return msg
+	}
+}
+
+// join joins under the name and reads the first state
+func join(t *testing.T, server *httptest.Server, name string) *websocket.Conn {
+	t.Helper()
+	conn := dial(t, server)
+	if err := conn.WriteJSON(clientMessage{Type: "join", Token: testToken, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, conn, "joined"); msg.PlayerID != "web:"+name {
+		t.Fatalf("got the player ID %q, want web:%s", msg.PlayerID, name)
+	}
+	return conn
+}
+
+func TestPlayRoutes(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		wantStatus int
+	}{
+		{"page", testToken, "/play", http.StatusOK},
+		{"page with a slash", testToken, "/play/", http.StatusOK},
+		{"socket without an upgrade", testToken, "/play/ws", http.StatusBadRequest},
+		{"unknown path", testToken, "/play/votes", http.StatusNotFound},
+		{"page without a token", "", "/play", http.StatusNotFound},
+		{"socket without a token", "", "/play/ws", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			PlayHandler{Handler: h, Token: tt.token}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJoinIsChecked(t *testing.T) {
+	server, _, _ := newPlayServer(t)
+
+	tests := []struct {
+		name      string
+		msg       clientMessage
+		wantError string
+	}{
+		{"vote before joining", clientMessage{Type: "vote", Move: "e4"}, "the first message must be a join message"},
+		{"wrong token", clientMessage{Type: "join", Token: "guess", Name: "alice"}, "invalid token"},
+		{"empty token", clientMessage{Type: "join", Name: "alice"}, "invalid token"},
+		{"name with spaces", clientMessage{Type: "join", Token: testToken, Name: "alice smith"}, "names are 1 to 20 letters, digits, - or _"},
+		{"mention as a name", clientMessage{Type: "join", Token: testToken, Name: "<@U1>"}, "names are 1 to 20 letters, digits, - or _"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dial(t, server)
+			if err := conn.WriteJSON(tt.msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg := receive(t, conn, "error"); msg.Error != tt.wantError {
+				t.Fatalf("got %q, want %q", msg.Error, tt.wantError)
+			}
+			// the connection is closed after a failed join
+			if _, _, err := conn.ReadMessage(); err == nil {
+				t.Fatal("got another message, want the connection closed")
+			}
+		})
+	}
+}
+
+func TestVotesAndEvents(t *testing.T) {
+	server, _, gm := newPlayServer(t)
+	alice := join(t, server, "alice")
+	if msg := receive(t, alice, "state"); msg.State.ID != "live" || len(msg.State.Votes) != 0 {
+		t.Fatalf("got %+v, want the active game without votes", msg.State)
+	}
+	bob := join(t, server, "bob")
+	receive(t, bob, "state")
+	waitForSubscribers(t, gm, 2)
+
+	if err := alice.WriteJSON(clientMessage{Type: "vote", Move: "e4"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, alice, "voted"); msg.Move != "e4" {
+		t.Fatalf("got %+v, want e4 voted", msg)
+	}
+	if votes := gm.Votes(); votes["web:alice"] != "e4" {
+		t.Fatalf("got the votes %v, want e4 from web:alice", votes)
+	}
+	// every player sees the vote
+	if msg := receive(t, bob, "state"); len(msg.State.Votes) != 1 || msg.State.Votes[0].Players[0] != "web:alice" {
+		t.Fatalf("got %+v, want the vote of web:alice", msg.State.Votes)
+	}
+
+	tests := []struct {
+		name      string
+		conn      *websocket.Conn
+		msg       clientMessage
+		wantError string
+	}{
+		{"second vote of the turn", alice, clientMessage{Type: "vote", Move: "d4"}, "You already voted for e4 this turn"},
+		{"illegal move", bob, clientMessage{Type: "vote", Move: "e5"}, "e5 is not a valid move"},
+		{"unknown type", bob, clientMessage{Type: "resign"}, `unknown message type "resign"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.conn.WriteJSON(tt.msg); err != nil {
+				t.Fatal(err)
+			}
+			for {
+				// states of the earlier votes may still be on their way
+				tt.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				var msg serverMessage
+				if err := tt.conn.ReadJSON(&msg); err != nil {
+					t.Fatal(err)
+				}
+				if msg.Type == "state" || msg.Type == "tick" {
+					continue
+				}
+				if msg.Type != "error" || msg.Error != tt.wantError {
+					t.Fatalf("got %+v, want the error %q", msg, tt.wantError)
+				}
+				break
+			}
+		})
+	}
+	if votes := gm.Votes(); len(votes) != 1 || votes["web:alice"] != "e4" {
+		t.Fatalf("got the votes %v, want only the first vote of web:alice", votes)
+	}
+
+	// the turn passes to the bot once the vote is over
+	if _, err := gm.MoveTopVote(); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "state"); !msg.State.BotTurn || msg.State.LastMove != "e4" {
+		t.Fatalf("got %+v, want the bot to move after e4", msg.State)
+	}
+	if err := bob.WriteJSON(clientMessage{Type: "vote", Move: "d4"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "error"); msg.Error != "It is the bot's turn" {
+		t.Fatalf("got %q, want the bot's turn", msg.Error)
+	}
+
+	// leaving stops following the game
+	alice.Close()
+	bob.Close()
+	waitForSubscribers(t, gm, 0)
+}
+
+func TestPlayersFollowTheGamesInPlay(t *testing.T) {
+	server, play, gm := newPlayServer(t)
+	conn := join(t, server, "alice")
+	receive(t, conn, "state")
+
+	play.GameStorage.RemoveGame()
+	receive(t, conn, "no_game")
+	waitForSubscribers(t, gm, 0)
+	if err := conn.WriteJSON(clientMessage{Type: "vote", Move: "e4"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, conn, "error"); msg.Error != "There isn't an active game at the moment" {
+		t.Fatalf("got %q", msg.Error)
+	}
+
+	next := game.NewGame("next", "white", game.Player{ID: "chessbot"}, game.Player{ID: "U1"})
+	if err := play.GameStorage.StoreGame(next); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, conn, "state"); msg.State.ID != "next" {
+		t.Fatalf("got %+v, want the new game", msg.State)
+	}
+	waitForSubscribers(t, next, 1)
+}
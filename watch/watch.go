@@ -0,0 +1,238 @@
+package watch
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+// tickInterval is how often the countdown is sent to the spectators
+const tickInterval = time.Second
+
+//go:embed templates/watch.html
+var templateFS embed.FS
+
+var page = template.Must(template.ParseFS(templateFS, "templates/watch.html"))
+
+// Handler serves the live spectator page of the active game under /watch
+// along with the Server-Sent Events stream that keeps it up to date
+type Handler struct {
+	GameStorage  game.ChessStorage
+	LinkRenderer rendering.RenderLink
+}
+
+// state is everything a spectator sees, it is sent whenever the game changes
+type state struct {
+	ID       string           `json:"id"`
+	FEN      string           `json:"fen"`
+	BoardURL string           `json:"board_url"`
+	LastMove string           `json:"last_move,omitempty"`
+	Turn     game.Color       `json:"turn"`
+	BotTurn  bool             `json:"bot_turn"`
+	Votes    []game.VoteCount `json:"votes"`
+	// VoteDeadline is when the top voted move gets played, missing while nobody has voted
+	VoteDeadline *time.Time `json:"vote_deadline,omitempty"`
+	SecondsLeft  *int       `json:"seconds_left,omitempty"`
+	// Outcome is set once the game is over, like 1-0 or 1/2-1/2
+	Outcome string `json:"outcome,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+// tick is the countdown of the current turn
+type tick struct {
+	SecondsLeft *int `json:"seconds_left"`
+}
+
+// end tells the spectators that the game is no longer played
+type end struct {
+	Reason string `json:"reason"`
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/watch"), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "":
+		h.redirectToActive(w, r)
+	case len(parts) == 1:
+		h.servePage(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "events":
+		h.serveEvents(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// redirectToActive sends spectators without a game ID to the game in play
+func (h Handler) redirectToActive(w http.ResponseWriter, r *http.Request) {
+	gm, err := h.GameStorage.RetrieveGame()
+	if err != nil {
+		http.Error(w, "There isn't an active game at the moment", http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, "/watch/"+gm.ID, http.StatusFound)
+}
+
+func (h Handler) servePage(w http.ResponseWriter, r *http.Request, id string) {
+	gm, ok := h.activeGame(id)
+	if !ok {
+		// finished games can still be looked at in the history
+		if _, err := h.GameStorage.Record(id); err == nil {
+			http.Redirect(w, r, "/games/"+id, http.StatusFound)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	var b strings.Builder
+	if err := page.Execute(&b, h.state(gm.Snapshot())); err != nil {
+		log.Println("could not render the watch page:", err)
+		http.Error(w, "could not render the page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, b.String())
+}
+
+// serveEvents streams the state of the game every time it changes and the countdown every second
+// until the game ends or the spectator leaves
+func (h Handler) serveEvents(w http.ResponseWriter, r *http.Request, id string) {
+	gm, ok := h.activeGame(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := gm.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keeps reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	send := func(event string, data interface{}) bool {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Println("could not encode the", event, "event:", err)
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	// sendState sends the current state and tells whether the stream should go on
+	sendState := func() bool {
+		current := h.state(gm.Snapshot())
+		if !send("state", current) {
+			return false
+		}
+		if current.Outcome != "" {
+			send("end", end{Reason: "the game is over"})
+			return false
+		}
+		return true
+	}
+
+	if !sendState() {
+		return
+	}
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-events:
+			if !sendState() {
+				return
+			}
+		case <-ticker.C:
+			// the game is removed from the store when nobody moves for a while
+			if _, ok := h.activeGame(id); !ok {
+				send("end", end{Reason: "the game was stopped"})
+				return
+			}
+			if !send("tick", tick{SecondsLeft: secondsLeft(gm.Snapshot())}) {
+				return
+			}
+		}
+	}
+}
+
+// activeGame returns the game in play if it has the given ID
+func (h Handler) activeGame(id string) (*game.Game, bool) {
+	gm, err := h.GameStorage.RetrieveGame()
+	if err != nil || gm.ID != id {
+		return nil, false
+	}
+	return gm, true
+}
+
+// state collects what the spectators see of a snapshot of the game
+func (h Handler) state(snapshot game.Snapshot) state {
+	s := state{
+		ID:          snapshot.ID,
+		FEN:         snapshot.FEN,
+		LastMove:    snapshot.LastMoveSAN,
+		Turn:        snapshot.Turn,
+		BotTurn:     snapshot.TurnPlayer().ID == "chessbot",
+		Votes:       game.TallyVotes(snapshot.Votes),
+		SecondsLeft: secondsLeft(snapshot),
+	}
+	if !snapshot.VoteDeadline.IsZero() {
+		deadline := snapshot.VoteDeadline
+		s.VoteDeadline = &deadline
+	}
+	if snapshot.Outcome != chess.NoOutcome {
+		s.Outcome = snapshot.Outcome.String()
+		s.Method = snapshot.Method.String()
+	}
+
+	link, err := h.LinkRenderer.CreateLink(snapshot, rendering.WithVotes(snapshot.Votes))
+	if err != nil {
+		log.Println("could not create the board link:", err)
+	} else {
+		s.BoardURL = link.String()
+	}
+	return s
+}
+
+// secondsLeft returns how many seconds are left to vote, nil while nobody has voted
+func secondsLeft(snapshot game.Snapshot) *int {
+	if snapshot.VoteDeadline.IsZero() {
+		return nil
+	}
+	seconds := int(time.Until(snapshot.VoteDeadline).Round(time.Second).Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	return &seconds
+}
@@ -0,0 +1,223 @@
+package watch
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyslexicat/collab-chess/game"
+	"github.com/dyslexicat/collab-chess/rendering"
+
+	"github.com/notnil/chess"
+)
+
+// newTestHandler returns a handler over a store with an active game "live" where the human players
+// play White, and a finished game "done"
+func newTestHandler(t *testing.T) (Handler, *game.Game) {
+	t.Helper()
+	store := game.NewMemoryStore()
+	gm := game.NewGame("live", "white", game.Player{ID: "chessbot"}, game.Player{ID: "U1"})
+	if err := store.StoreGame(gm); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveRecord(game.Record{ID: "done", Result: game.Win}); err != nil {
+		t.Fatal(err)
+	}
+	return Handler{GameStorage: store, LinkRenderer: rendering.NewRenderLink("https://chess.example", "test-key")}, gm
+}
+
+// sseEvent is a single event of a Server-Sent Events stream
+type sseEvent struct {
+	Name string
+	Data string
+}
+
+// readEvent reads the next event of the stream
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("the stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.Name != "":
+			return event
+		case strings.HasPrefix(line, "event: "):
+			event.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// readState reads events until a state event and returns it, skipping the countdown
+func readState(t *testing.T, r *bufio.Reader) state {
+	t.Helper()
+	for {
+		event := readEvent(t, r)
+		if event.Name == "tick" {
+			continue
+		}
+		if event.Name != "state" {
+			t.Fatalf("got a %s event, want a state: %s", event.Name, event.Data)
+		}
+		var s state
+		if err := json.Unmarshal([]byte(event.Data), &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+}
+
+// waitForSubscribers waits until the game has n subscribers
+func waitForSubscribers(t *testing.T, gm *game.Game, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for gm.Subscribers() != n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := gm.Subscribers(); got != n {
+		t.Fatalf("got %d subscribers, want %d", got, n)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	tests := []struct {
+		method       string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{http.MethodGet, "/watch", http.StatusFound, "/watch/live"},
+		{http.MethodGet, "/watch/live", http.StatusOK, ""},
+		{http.MethodGet, "/watch/done", http.StatusFound, "/games/done"},
+		{http.MethodGet, "/watch/missing", http.StatusNotFound, ""},
+		{http.MethodGet, "/watch/done/events", http.StatusNotFound, ""},
+		{http.MethodGet, "/watch/missing/events", http.StatusNotFound, ""},
+		{http.MethodGet, "/watch/live/votes", http.StatusNotFound, ""},
+		{http.MethodPost, "/watch/live", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Fatalf("got the location %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+
+	// without a game in play there is nothing to watch
+	h.GameStorage.RemoveGame()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestEventsFollowTheVotes(t *testing.T) {
+	h, gm := newTestHandler(t)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/watch/live/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("got content type %q, want an event stream", got)
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	s := readState(t, stream)
+	if s.ID != "live" || s.Turn != game.White || s.BotTurn || len(s.Votes) != 0 || s.SecondsLeft != nil || s.BoardURL == "" {
+		t.Fatalf("got %+v, want the starting position without votes", s)
+	}
+	waitForSubscribers(t, gm, 1)
+
+	if err := gm.Vote("U1", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	s = readState(t, stream)
+	if len(s.Votes) != 1 || s.Votes[0].Move != "e4" || s.Votes[0].Players[0] != "U1" || s.VoteDeadline == nil || s.SecondsLeft == nil {
+		t.Fatalf("got %+v, want the vote for e4 with a countdown", s)
+	}
+	if event := readEvent(t, stream); event.Name != "tick" || !strings.HasPrefix(event.Data, `{"seconds_left":`) {
+		t.Fatalf("got %+v, want the countdown", event)
+	}
+
+	// the spectator leaving stops the subscription
+	resp.Body.Close()
+	waitForSubscribers(t, gm, 0)
+}
+
+func TestEventsEndWithTheGame(t *testing.T) {
+	h, gm := newTestHandler(t)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/watch/live/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	stream := bufio.NewReader(resp.Body)
+	readState(t, stream)
+
+	// the game is removed when nobody moves for a while
+	h.GameStorage.RemoveGame()
+	if event := readEvent(t, stream); event.Name != "end" || event.Data != `{"reason":"the game was stopped"}` {
+		t.Fatalf("got %+v, want the end of the game", event)
+	}
+	waitForSubscribers(t, gm, 0)
+}
+
+func TestEventsOfAFinishedGame(t *testing.T) {
+	h, gm := newTestHandler(t)
+	for _, san := range []string{"f3", "e5", "g4", "Qh4#"} {
+		if gm.TurnPlayer().ID != "chessbot" {
+			if err := gm.Vote("U1", san); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := gm.MoveTopVote(); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		move, err := chess.AlgebraicNotation{}.Decode(gm.Snapshot().Position, san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := gm.BotMove(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch/live/events", nil))
+	stream := bufio.NewReader(w.Body)
+	if s := readState(t, stream); s.Outcome != "0-1" || s.Method != "Checkmate" || s.LastMove != "Qh4#" {
+		t.Fatalf("got %+v, want the checkmate", s)
+	}
+	if event := readEvent(t, stream); event.Name != "end" || event.Data != `{"reason":"the game is over"}` {
+		t.Fatalf("got %+v, want the end of the game", event)
+	}
+	if gm.Subscribers() != 0 {
+		t.Fatalf("got %d subscribers, want none once the stream ended", gm.Subscribers())
+	}
+}